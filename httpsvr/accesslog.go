@@ -0,0 +1,216 @@
+package httpsvr
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hydah/golib/logger"
+)
+
+// Formats of AccessLog, any other format is a template, see AccessLogOptions.
+const (
+	AccessLogCommon   = "common"
+	AccessLogCombined = "combined"
+	AccessLogJSON     = "json"
+)
+
+// AccessLogOptions configures AccessLog.
+type AccessLogOptions struct {
+	// Format is AccessLogCommon (default), AccessLogCombined, AccessLogJSON,
+	// or a template where ${field} is replaced by one of the fields
+	// time, remote_ip, user, host, method, uri, path, proto, status, bytes,
+	// latency, latency_ms, referer, user_agent, route and request_id,
+	// e.g. "${method} ${route} ${status} ${latency}".
+	Format string
+	// Writer receives the access log records, default the logger filters.
+	Writer logger.LogWriter
+	// Level of the records, default INFO. The zero value, DEBUG, stands for
+	// the default: access lines are not debug output.
+	Level logger.Level
+	// SkipPaths are not logged, e.g. "/healthz".
+	SkipPaths []string
+	// Skip returns true for the requests that are not logged.
+	Skip func(*Context) bool
+}
+
+// accessEntry holds the fields of one access log line.
+type accessEntry struct {
+	Time      time.Time `json:"time"`
+	RemoteIP  string    `json:"remote_ip"`
+	User      string    `json:"user,omitempty"`
+	Host      string    `json:"host"`
+	Method    string    `json:"method"`
+	URI       string    `json:"uri"`
+	Path      string    `json:"path"`
+	Proto     string    `json:"proto"`
+	Status    int       `json:"status"`
+	Bytes     int       `json:"bytes"`
+	Latency   float64   `json:"latency_ms"`
+	Referer   string    `json:"referer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Route     string    `json:"route,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+
+	latency time.Duration
+}
+
+// AccessLog returns a middleware writing one line per request, unlike
+// Logger it writes plain lines at a configurable level. The request id is
+// known when RequestID is used too, before or after AccessLog.
+func AccessLog(opts AccessLogOptions) HandlerFunc {
+	level := opts.Level
+	if level == logger.DEBUG {
+		level = logger.INFO
+	}
+	format := accessLogFormat(opts.Format)
+	skip := make(map[string]bool, len(opts.SkipPaths))
+	for _, path := range opts.SkipPaths {
+		skip[path] = true
+	}
+
+	return func(ctx *Context) {
+		if skip[ctx.Req.URL.Path] || opts.Skip != nil && opts.Skip(ctx) {
+			return
+		}
+		start := time.Now()
+		ctx.Next()
+
+		e := newAccessEntry(ctx, start)
+		line := format(e)
+		rec := &logger.LogRecord{
+			Level:     level,
+			Created:   e.Time,
+			Source:    "access",
+			Message:   line,
+			RequestID: e.RequestID,
+		}
+		if opts.Writer == nil {
+			logger.WriteRecord(rec)
+			return
+		}
+		opts.Writer.LogWrite(rec)
+	}
+}
+
+func newAccessEntry(ctx *Context, start time.Time) *accessEntry {
+	req := ctx.Req
+	e := &accessEntry{
+		Time:      start,
+		RemoteIP:  ctx.ClientIP(),
+		Host:      req.Host,
+		Method:    req.Method,
+		URI:       req.RequestURI,
+		Path:      req.URL.Path,
+		Proto:     req.Proto,
+		Status:    ctx.Writer.Status(),
+		Bytes:     ctx.Writer.Size(),
+		Referer:   req.Referer(),
+		UserAgent: req.UserAgent(),
+		Route:     ctx.Route(),
+		RequestID: ctx.RequestID(),
+		latency:   time.Since(start),
+	}
+	if e.URI == "" {
+		e.URI = req.URL.RequestURI()
+	}
+	if e.Bytes < 0 {
+		e.Bytes = 0
+	}
+	if user, _, ok := req.BasicAuth(); ok {
+		e.User = user
+	}
+	e.Latency = float64(e.latency) / float64(time.Millisecond)
+	return e
+}
+
+func accessLogFormat(format string) func(*accessEntry) string {
+	switch format {
+	case "", AccessLogCommon:
+		return formatCommon
+	case AccessLogCombined:
+		return func(e *accessEntry) string {
+			return formatCommon(e) + " " + strconv.Quote(dash(e.Referer)) + " " + strconv.Quote(dash(e.UserAgent))
+		}
+	case AccessLogJSON:
+		return func(e *accessEntry) string {
+			b, _ := json.Marshal(e)
+			return string(b)
+		}
+	}
+	return compileAccessTemplate(format)
+}
+
+// formatCommon writes the Apache common log format.
+func formatCommon(e *accessEntry) string {
+	bytes := "-"
+	if e.Bytes > 0 {
+		bytes = strconv.Itoa(e.Bytes)
+	}
+	return e.RemoteIP + " - " + dash(e.User) + " [" + e.Time.Format("02/Jan/2006:15:04:05 -0700") + "] " +
+		strconv.Quote(e.Method+" "+e.URI+" "+e.Proto) + " " + strconv.Itoa(e.Status) + " " + bytes
+}
+
+var accessFields = map[string]func(*accessEntry) string{
+	"time":       func(e *accessEntry) string { return e.Time.Format(time.RFC3339) },
+	"remote_ip":  func(e *accessEntry) string { return e.RemoteIP },
+	"user":       func(e *accessEntry) string { return dash(e.User) },
+	"host":       func(e *accessEntry) string { return e.Host },
+	"method":     func(e *accessEntry) string { return e.Method },
+	"uri":        func(e *accessEntry) string { return e.URI },
+	"path":       func(e *accessEntry) string { return e.Path },
+	"proto":      func(e *accessEntry) string { return e.Proto },
+	"status":     func(e *accessEntry) string { return strconv.Itoa(e.Status) },
+	"bytes":      func(e *accessEntry) string { return strconv.Itoa(e.Bytes) },
+	"latency":    func(e *accessEntry) string { return e.latency.String() },
+	"latency_ms": func(e *accessEntry) string { return strconv.FormatFloat(e.Latency, 'f', 3, 64) },
+	"referer":    func(e *accessEntry) string { return dash(e.Referer) },
+	"user_agent": func(e *accessEntry) string { return dash(e.UserAgent) },
+	"route":      func(e *accessEntry) string { return dash(e.Route) },
+	"request_id": func(e *accessEntry) string { return dash(e.RequestID) },
+}
+
+// compileAccessTemplate splits the template once into literals and fields,
+// an unknown field panics.
+func compileAccessTemplate(format string) func(*accessEntry) string {
+	var parts []func(*accessEntry) string
+	for format != "" {
+		start := strings.Index(format, "${")
+		end := -1
+		if start >= 0 {
+			end = strings.Index(format[start:], "}")
+		}
+		if end < 0 {
+			literal := format
+			parts = append(parts, func(*accessEntry) string { return literal })
+			break
+		}
+		if start > 0 {
+			literal := format[:start]
+			parts = append(parts, func(*accessEntry) string { return literal })
+		}
+		end += start
+		name := format[start+2 : end]
+		field, ok := accessFields[name]
+		if !ok {
+			panic("httpsvr: unknown access log field " + name)
+		}
+		parts = append(parts, field)
+		format = format[end+1:]
+	}
+	return func(e *accessEntry) string {
+		var b strings.Builder
+		for _, part := range parts {
+			b.WriteString(part(e))
+		}
+		return b.String()
+	}
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,91 @@
+package httpsvr
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hydah/golib/logger"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_AccessLog(t *testing.T) {
+	serve := func(opts AccessLogOptions, path string) *recordWriter {
+		w := &recordWriter{}
+		opts.Writer = w
+		e := New()
+		e.Use(AccessLog(opts), RequestID(func() string { return "rid" }))
+		e.GET("/users/:id", func(ctx *Context) { ctx.Text("hello") })
+		e.GET("/healthz", func(ctx *Context) {})
+
+		req, _ := http.NewRequest("GET", path, nil)
+		req.RemoteAddr = "10.0.0.1:5000"
+		req.Header.Set("Referer", "http://example.com/")
+		req.Header.Set("User-Agent", "test-agent")
+		req.SetBasicAuth("alice", "secret")
+		e.ServeHTTP(httptest.NewRecorder(), req)
+		return w
+	}
+
+	Convey("Common and combined formats", t, func() {
+		w := serve(AccessLogOptions{}, "/users/7?full=1")
+		So(len(w.records), ShouldEqual, 1)
+		So(w.records[0].Level, ShouldEqual, logger.INFO)
+		So(w.records[0].RequestID, ShouldEqual, "rid")
+		line := w.records[0].Message
+		So(line, ShouldStartWith, "10.0.0.1 - alice [")
+		So(line, ShouldEndWith, `] "GET /users/7?full=1 HTTP/1.1" 200 5`)
+
+		w = serve(AccessLogOptions{Format: AccessLogCombined, Level: logger.WARNING}, "/users/7")
+		So(w.records[0].Level, ShouldEqual, logger.WARNING)
+		So(w.records[0].Message, ShouldEndWith, `200 5 "http://example.com/" "test-agent"`)
+		So(accessLogFormat(AccessLogCombined)(&accessEntry{Status: 200}), ShouldEndWith, `200 - "-" "-"`)
+
+		w = serve(AccessLogOptions{Level: logger.TRACE}, "/users/7")
+		So(w.records[0].Level, ShouldEqual, logger.TRACE)
+	})
+
+	Convey("JSON format", t, func() {
+		w := serve(AccessLogOptions{Format: AccessLogJSON}, "/users/7")
+		var entry map[string]interface{}
+		So(json.Unmarshal([]byte(w.records[0].Message), &entry), ShouldBeNil)
+		So(entry["route"], ShouldEqual, "/users/:id")
+		So(entry["status"], ShouldEqual, 200)
+		So(entry["bytes"], ShouldEqual, 5)
+		So(entry["request_id"], ShouldEqual, "rid")
+		So(entry["user_agent"], ShouldEqual, "test-agent")
+		So(entry["latency_ms"], ShouldNotBeNil)
+	})
+
+	Convey("The logger filters get the line untouched", t, func() {
+		w := &recordWriter{}
+		logger.AddFilter("access_log_test", logger.INFO, w)
+		defer delete(logger.Global, "access_log_test")
+		e := New()
+		e.Use(RequestID(func() string { return "rid" }), AccessLog(AccessLogOptions{Format: AccessLogJSON}))
+		e.GET("/", func(ctx *Context) {})
+		req, _ := http.NewRequest("GET", "/", nil)
+		e.ServeHTTP(httptest.NewRecorder(), req)
+
+		So(len(w.records), ShouldEqual, 1)
+		So(w.records[0].RequestID, ShouldEqual, "rid")
+		So(w.records[0].Source, ShouldEqual, "access")
+		var entry map[string]interface{}
+		So(json.Unmarshal([]byte(w.records[0].Message), &entry), ShouldBeNil)
+	})
+
+	Convey("Template format", t, func() {
+		w := serve(AccessLogOptions{Format: "${method} ${route} {${status}} ${request_id} ${bytes}B"}, "/users/7")
+		So(w.records[0].Message, ShouldEqual, "GET /users/:id {200} rid 5B")
+
+		So(func() { AccessLog(AccessLogOptions{Format: "${nope}"}) }, ShouldPanic)
+	})
+
+	Convey("Skipped paths are not logged", t, func() {
+		So(len(serve(AccessLogOptions{SkipPaths: []string{"/healthz"}}, "/healthz").records), ShouldEqual, 0)
+		skip := func(ctx *Context) bool { return strings.HasPrefix(ctx.Req.URL.Path, "/users") }
+		So(len(serve(AccessLogOptions{Skip: skip}, "/users/1").records), ShouldEqual, 0)
+	})
+}
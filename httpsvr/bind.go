@@ -0,0 +1,87 @@
+package httpsvr
+
+import (
+	"github.com/hydah/golib/httpsvr/binding"
+)
+
+// BindErrorKey is the Context.Keys entry holding the last bind error.
+const BindErrorKey = "httpsvr/bind-error"
+
+// Bind decodes the request into obj with a binding picked by the method and
+// Content-Type (see binding.Default) and validates it against the `binding`
+// tags. The error, usually binding.Errors, is also kept in Context.Keys so
+// that the BindErrors middleware can answer with a 400. A body over the limit
+// of BodyLimit or Decompress returns ErrBodyTooLarge and is answered 413.
+func (c *Context) Bind(obj interface{}) error {
+	return c.BindWith(obj, binding.Default(c.Req.Method, c.Req.Header.Get("Content-Type")))
+}
+
+// BindQuery binds the url query using the `form` tag.
+func (c *Context) BindQuery(obj interface{}) error {
+	return c.BindWith(obj, binding.Query)
+}
+
+// BindForm binds the query and the body form using the `form` tag.
+func (c *Context) BindForm(obj interface{}) error {
+	return c.BindWith(obj, binding.Form)
+}
+
+// BindJSON binds the JSON body.
+func (c *Context) BindJSON(obj interface{}) error {
+	return c.BindWith(obj, binding.JSON)
+}
+
+// BindXML binds the XML body.
+func (c *Context) BindXML(obj interface{}) error {
+	return c.BindWith(obj, binding.XML)
+}
+
+// BindURI binds the path parameters using the `uri` tag.
+func (c *Context) BindURI(obj interface{}) error {
+	params := make(map[string][]string, len(c.Params))
+	for _, p := range c.Params {
+		params[p.Key] = []string{p.Value}
+	}
+	return c.bindError(binding.BindURI(params, obj))
+}
+
+// BindWith binds the request into obj with the given binding.
+func (c *Context) BindWith(obj interface{}, b binding.Binding) error {
+	return c.bindError(b.Bind(c.Req, obj))
+}
+
+// bindError records err for BindErrors, ErrBodyTooLarge is answered at once.
+func (c *Context) bindError(err error) error {
+	if err == ErrBodyTooLarge {
+		bodyTooLarge(c)
+	}
+	if err != nil {
+		c.Set(BindErrorKey, err)
+	}
+	return err
+}
+
+// BindErrors returns a middleware that answers with a 400 JSON body, or a 413
+// for ErrBodyTooLarge, when a handler returned on a bind error without
+// writing a response:
+//
+//	{"error": "...", "fields": [{"field": "Name", "tag": "required", "message": "..."}]}
+func BindErrors() HandlerFunc {
+	return func(ctx *Context) {
+		ctx.Next()
+		v, err := ctx.Get(BindErrorKey)
+		if err != nil || ctx.Writer.Written() {
+			return
+		}
+		bindErr := v.(error)
+		if bindErr == ErrBodyTooLarge {
+			bodyTooLarge(ctx)
+			return
+		}
+		body := JSON{"error": bindErr.Error()}
+		if errs, ok := bindErr.(binding.Errors); ok {
+			body["fields"] = errs
+		}
+		ctx.Json(body, 400)
+	}
+}
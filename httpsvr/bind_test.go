@@ -0,0 +1,80 @@
+package httpsvr
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type bindUserURI struct {
+	ID int `uri:"id" binding:"required,min=1"`
+}
+
+type bindUser struct {
+	Name string `json:"name" binding:"required"`
+}
+
+func Test_Bind(t *testing.T) {
+	Convey("Bind uri and body, answer 400 on failure", t, func() {
+		m := New()
+		m.Use(BindErrors())
+		m.POST("/users/:id", func(ctx *Context) {
+			var uri bindUserURI
+			var u bindUser
+			if ctx.BindURI(&uri) != nil || ctx.Bind(&u) != nil {
+				return
+			}
+			ctx.Json(JSON{"id": uri.ID, "name": u.Name})
+		})
+
+		w := bindRequest(m, "/users/3", `{"name":"http"}`)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Body.String(), ShouldEqual, `{"id":3,"name":"http"}`)
+
+		w = bindRequest(m, "/users/-1", `{"name":"http"}`)
+		So(w.Code, ShouldEqual, http.StatusBadRequest)
+		So(w.Body.String(), ShouldContainSubstring, `"field":"ID","tag":"min"`)
+
+		w = bindRequest(m, "/users/3", `{}`)
+		So(w.Code, ShouldEqual, http.StatusBadRequest)
+		So(w.Body.String(), ShouldContainSubstring, `"field":"Name","tag":"required"`)
+
+		w = bindRequest(m, "/users/3", `{`)
+		So(w.Code, ShouldEqual, http.StatusBadRequest)
+	})
+
+	Convey("Bodies over the limit are answered 413", t, func() {
+		var bindErr error
+		m := New()
+		m.Use(BindErrors(), BodyLimit(8))
+		m.POST("/users", func(ctx *Context) {
+			var u bindUser
+			if bindErr = ctx.BindJSON(&u); bindErr != nil {
+				return
+			}
+			ctx.Json(JSON{"name": u.Name})
+		})
+		m.POST("/recorded", func(ctx *Context) { ctx.Set(BindErrorKey, ErrBodyTooLarge) })
+
+		// a reader of unknown length, read past the limit by the binding
+		body := io.MultiReader(strings.NewReader(`{"name":"a long name"}`))
+		w := performRequestWith(m, "POST", "/users", http.Header{"Content-Type": {"application/json"}}, body)
+		So(bindErr, ShouldEqual, ErrBodyTooLarge)
+		So(w.Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+		So(w.Body.String(), ShouldEqual, http.StatusText(http.StatusRequestEntityTooLarge))
+
+		So(performRequest(m, "POST", "/recorded").Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+	})
+}
+
+func bindRequest(m *Engine, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	return w
+}
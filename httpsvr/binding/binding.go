@@ -0,0 +1,160 @@
+package binding
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+type (
+	// Binding decodes a request into a struct and validates it.
+	Binding interface {
+		Name() string
+		Bind(*http.Request, interface{}) error
+	}
+	jsonBinding  struct{}
+	xmlBinding   struct{}
+	formBinding  struct{}
+	queryBinding struct{}
+)
+
+const (
+	// MIMEJSON is the content type of a JSON request body.
+	MIMEJSON = "application/json"
+	// MIMEXML is the content type of a XML request body.
+	MIMEXML = "application/xml"
+	// MIMEXML2 is the alternative content type of a XML request body.
+	MIMEXML2 = "text/xml"
+	// MIMEPOSTForm is the content type of an urlencoded form.
+	MIMEPOSTForm = "application/x-www-form-urlencoded"
+	// MIMEMultipartPOSTForm is the content type of a multipart form.
+	MIMEMultipartPOSTForm = "multipart/form-data"
+)
+
+// maxMemory is the memory limit when parsing a multipart form.
+var maxMemory int64 = 1 << 26
+
+var (
+	JSON  Binding = jsonBinding{}
+	XML   Binding = xmlBinding{}
+	Form  Binding = formBinding{}
+	Query Binding = queryBinding{}
+)
+
+// Default returns the binding for the given method and content type:
+// Query for GET, HEAD and DELETE, otherwise decided by the content type.
+func Default(method, contentType string) Binding {
+	if method == "GET" || method == "HEAD" || method == "DELETE" {
+		return Query
+	}
+	switch filterFlags(contentType) {
+	case MIMEJSON:
+		return JSON
+	case MIMEXML, MIMEXML2:
+		return XML
+	default:
+		return Form
+	}
+}
+
+func (jsonBinding) Name() string {
+	return "json"
+}
+
+func (jsonBinding) Bind(req *http.Request, obj interface{}) error {
+	if req.Body == nil {
+		return errors.New("empty request body")
+	}
+	if err := json.NewDecoder(req.Body).Decode(obj); err != nil {
+		return err
+	}
+	return Validate(obj)
+}
+
+func (xmlBinding) Name() string {
+	return "xml"
+}
+
+func (xmlBinding) Bind(req *http.Request, obj interface{}) error {
+	if req.Body == nil {
+		return errors.New("empty request body")
+	}
+	if err := xml.NewDecoder(req.Body).Decode(obj); err != nil {
+		return err
+	}
+	return Validate(obj)
+}
+
+func (formBinding) Name() string {
+	return "form"
+}
+
+// Bind maps the query and body form values, multipart forms included.
+func (formBinding) Bind(req *http.Request, obj interface{}) error {
+	if strings.HasPrefix(filterFlags(req.Header.Get("Content-Type")), MIMEMultipartPOSTForm) {
+		if err := req.ParseMultipartForm(maxMemory); err != nil {
+			return err
+		}
+	} else if err := req.ParseForm(); err != nil {
+		return err
+	}
+	return mapAndValidate(obj, req.Form, "form")
+}
+
+func (queryBinding) Name() string {
+	return "query"
+}
+
+// Bind maps the url query values only.
+func (queryBinding) Bind(req *http.Request, obj interface{}) error {
+	return mapAndValidate(obj, req.URL.Query(), "form")
+}
+
+// BindURI maps path parameters into obj using the `uri` tag.
+func BindURI(params map[string][]string, obj interface{}) error {
+	return mapAndValidate(obj, params, "uri")
+}
+
+func mapAndValidate(obj interface{}, values map[string][]string, tag string) error {
+	err := MapForm(obj, values, tag)
+	if err != nil {
+		if _, ok := err.(Errors); !ok {
+			return err
+		}
+	}
+	return merge(err, Validate(obj))
+}
+
+// merge joins the field errors of a and b, a field reported by a is not
+// reported again by b.
+func merge(a, b error) error {
+	errsA, _ := a.(Errors)
+	errsB, ok := b.(Errors)
+	if !ok {
+		if len(errsA) > 0 {
+			return errsA
+		}
+		return b
+	}
+	seen := make(map[string]bool, len(errsA))
+	for _, e := range errsA {
+		seen[e.Field] = true
+	}
+	for _, e := range errsB {
+		if !seen[e.Field] {
+			errsA = append(errsA, e)
+		}
+	}
+	return errsA
+}
+
+func filterFlags(content string) string {
+	for i, char := range content {
+		if char == ' ' || char == ';' {
+			return content[:i]
+		}
+	}
+	return content
+}
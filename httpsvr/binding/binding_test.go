@@ -0,0 +1,148 @@
+package binding
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type address struct {
+	City string `form:"city" json:"city" binding:"required"`
+}
+
+type user struct {
+	Name    string        `form:"name" json:"name" binding:"required,min=2,max=8"`
+	Age     int           `form:"age" json:"age" binding:"min=18,max=120"`
+	Role    string        `form:"role" json:"role" binding:"oneof=admin user"`
+	Email   string        `form:"email" json:"email" binding:"email"`
+	Code    string        `form:"code" json:"code" binding:"len=4,regexp=^[0-9]{2,4}$"`
+	Tags    []string      `form:"tag" json:"tags" binding:"max=2"`
+	Born    time.Time     `form:"born" time_format:"2006-01-02"`
+	Timeout time.Duration `form:"timeout"`
+	Score   *float64      `form:"score"`
+	Address address       `json:"address"`
+}
+
+func Test_MapForm(t *testing.T) {
+	Convey("Map form values into a struct", t, func() {
+		var u user
+		err := MapForm(&u, url.Values{
+			"name":    {"http"},
+			"age":     {"20"},
+			"tag":     {"a", "b"},
+			"born":    {"2018-01-02"},
+			"timeout": {"3s"},
+			"score":   {"1.5"},
+			"city":    {"sz"},
+		}, "form")
+		So(err, ShouldBeNil)
+		So(u.Name, ShouldEqual, "http")
+		So(u.Age, ShouldEqual, 20)
+		So(u.Tags, ShouldResemble, []string{"a", "b"})
+		So(u.Born.Day(), ShouldEqual, 2)
+		So(u.Timeout, ShouldEqual, 3*time.Second)
+		So(*u.Score, ShouldEqual, 1.5)
+		So(u.Address.City, ShouldEqual, "sz")
+
+		err = MapForm(&u, url.Values{"age": {"x"}, "born": {"bad"}}, "form")
+		errs, ok := err.(Errors)
+		So(ok, ShouldBeTrue)
+		So(len(errs), ShouldEqual, 2)
+		So(errs[0].Field, ShouldEqual, "Age")
+		So(errs[0].Tag, ShouldEqual, "type")
+
+		So(MapForm(u, nil, "form"), ShouldNotBeNil)
+	})
+}
+
+func Test_Validate(t *testing.T) {
+	Convey("Valid struct", t, func() {
+		u := user{Name: "http", Age: 30, Role: "admin", Email: "a@b.com", Code: "1234", Address: address{City: "sz"}}
+		So(Validate(&u), ShouldBeNil)
+	})
+	Convey("Every failed field is reported", t, func() {
+		u := user{Name: "h", Age: 10, Role: "root", Email: "nope", Code: "12a", Tags: []string{"a", "b", "c"}}
+		errs, ok := Validate(&u).(Errors)
+		So(ok, ShouldBeTrue)
+		fields := map[string]string{}
+		for _, e := range errs {
+			fields[e.Field] = e.Tag
+		}
+		So(fields, ShouldResemble, map[string]string{
+			"Name":         "min",
+			"Age":          "min",
+			"Role":         "oneof",
+			"Email":        "email",
+			"Code":         "len",
+			"Tags":         "max",
+			"Address.City": "required",
+		})
+	})
+	Convey("Zero values only fail required", t, func() {
+		errs := Validate(&user{Address: address{City: "sz"}}).(Errors)
+		So(len(errs), ShouldEqual, 1)
+		So(errs[0].Tag, ShouldEqual, "required")
+	})
+	Convey("Unexported embedded structs are validated", t, func() {
+		type level struct {
+			Level string `binding:"oneof=low high"`
+		}
+		type alert struct {
+			level `binding:"required"`
+		}
+		So(Validate(&alert{level{Level: "high"}}), ShouldBeNil)
+		errs := Validate(&alert{}).(Errors)
+		So(errs[0].Tag, ShouldEqual, "required")
+		So(errs[0].Value, ShouldBeNil)
+		errs = Validate(&alert{level{Level: "mid"}}).(Errors)
+		So(errs[0].Field, ShouldEqual, "Level")
+	})
+	Convey("Bad tags panic on the first validation of the type", t, func() {
+		type unknown struct {
+			Name string `binding:"requird"`
+		}
+		type badParam struct {
+			Age int `binding:"min=ten"`
+		}
+		type badKind struct {
+			Ok bool `binding:"max=1"`
+		}
+		So(func() { Validate(&unknown{Name: "x"}) }, ShouldPanic)
+		So(func() { Validate(&badParam{}) }, ShouldPanic)
+		So(func() { Validate(&badKind{}) }, ShouldPanic)
+	})
+}
+
+func Test_Bind(t *testing.T) {
+	Convey("JSON body", t, func() {
+		req, _ := http.NewRequest("POST", "/", strings.NewReader(`{"name":"http","age":30,"address":{"city":"sz"}}`))
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		var u user
+		b := Default(req.Method, req.Header.Get("Content-Type"))
+		So(b.Name(), ShouldEqual, "json")
+		So(b.Bind(req, &u), ShouldBeNil)
+		So(u.Address.City, ShouldEqual, "sz")
+	})
+	Convey("Form body", t, func() {
+		req, _ := http.NewRequest("POST", "/?age=5", strings.NewReader("name=http&city=sz"))
+		req.Header.Set("Content-Type", MIMEPOSTForm)
+		var u user
+		errs, ok := Default(req.Method, req.Header.Get("Content-Type")).Bind(req, &u).(Errors)
+		So(ok, ShouldBeTrue)
+		So(len(errs), ShouldEqual, 1)
+		So(errs[0].Field, ShouldEqual, "Age")
+		So(u.Name, ShouldEqual, "http")
+	})
+	Convey("Query for GET", t, func() {
+		req, _ := http.NewRequest("GET", "/?name=http&age=x&city=sz", nil)
+		var u user
+		errs, ok := Default(req.Method, "").Bind(req, &u).(Errors)
+		So(ok, ShouldBeTrue)
+		So(len(errs), ShouldEqual, 1)
+		So(errs[0].Tag, ShouldEqual, "type")
+	})
+}
@@ -0,0 +1,168 @@
+package binding
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var errNotStructPtr = errors.New("binding: obj must be a pointer to a struct")
+
+// MapForm fills the struct pointed to by obj from values, the value key of a
+// field is its tag value or the field name. Nested and embedded structs are
+// filled recursively, `time_format` sets the layout of a time.Time field.
+// Values that cannot be converted are reported as a "type" FieldError.
+func MapForm(obj interface{}, values map[string][]string, tag string) error {
+	rv := reflect.ValueOf(obj)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errNotStructPtr
+	}
+	var errs Errors
+	mapStruct(rv.Elem(), values, tag, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func mapStruct(rv reflect.Value, values map[string][]string, tag, ns string, errs *Errors) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		name := field.Tag.Get(tag)
+		if name == "-" {
+			continue
+		}
+		fv := rv.Field(i)
+		if field.Type.Kind() == reflect.Struct && field.Type != timeType {
+			if field.Anonymous {
+				mapStruct(fv, values, tag, ns, errs)
+			} else {
+				mapStruct(fv, values, tag, ns+field.Name+".", errs)
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		vs, ok := values[name]
+		if !ok || len(vs) == 0 {
+			continue
+		}
+		if err := setField(fv, field, vs); err != nil {
+			*errs = append(*errs, &FieldError{
+				Field:   ns + field.Name,
+				Tag:     "type",
+				Param:   field.Type.String(),
+				Value:   vs[0],
+				Message: err.Error(),
+			})
+		}
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func setField(fv reflect.Value, field reflect.StructField, vs []string) error {
+	if !fv.CanSet() {
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.Slice:
+		slice := reflect.MakeSlice(fv.Type(), len(vs), len(vs))
+		for i, v := range vs {
+			if err := setValue(slice.Index(i), field, v); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	case reflect.Ptr:
+		ptr := reflect.New(fv.Type().Elem())
+		if err := setValue(ptr.Elem(), field, vs[0]); err != nil {
+			return err
+		}
+		fv.Set(ptr)
+		return nil
+	default:
+		return setValue(fv, field, vs[0])
+	}
+}
+
+func setValue(fv reflect.Value, field reflect.StructField, val string) error {
+	if fv.Type() == timeType {
+		return setTime(fv, field, val)
+	}
+	if _, ok := fv.Interface().(time.Duration); ok {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Bool:
+		if val == "" {
+			val = "false"
+		}
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if val == "" {
+			val = "0"
+		}
+		n, err := strconv.ParseInt(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if val == "" {
+			val = "0"
+		}
+		n, err := strconv.ParseUint(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if val == "" {
+			val = "0"
+		}
+		f, err := strconv.ParseFloat(val, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return errors.New("unsupported type " + fv.Type().String())
+	}
+	return nil
+}
+
+func setTime(fv reflect.Value, field reflect.StructField, val string) error {
+	if val == "" {
+		fv.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+	layout := field.Tag.Get("time_format")
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	t, err := time.ParseInLocation(layout, val, time.Local)
+	if err != nil {
+		return err
+	}
+	fv.Set(reflect.ValueOf(t))
+	return nil
+}
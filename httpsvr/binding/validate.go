@@ -0,0 +1,394 @@
+package binding
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// FieldError describes one field that failed binding or validation.
+type FieldError struct {
+	Field   string      `json:"field"`
+	Tag     string      `json:"tag"`
+	Param   string      `json:"param,omitempty"`
+	Value   interface{} `json:"-"`
+	Message string      `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+// Errors lists every field that failed binding or validation.
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks the struct pointed to by obj against the `binding` tags of
+// its fields, for example:
+//
+//	Name  string `binding:"required,min=2,max=32"`
+//	Role  string `binding:"oneof=admin user"`
+//	Mail  string `binding:"email"`
+//	Code  string `binding:"len=6,regexp=^[0-9]+$"`
+//
+// min, max and len compare the value of numbers and the length of strings,
+// slices and maps. Rules other than required are skipped for zero values.
+// A regexp must be the last rule as it may contain commas.
+// Nested structs, pointers to structs and slices of structs are validated
+// recursively. It returns nil or Errors.
+//
+// The tags of a struct type are parsed once, on its first validation, which
+// panics on an unknown rule, a bad parameter or a rule not fitting the type
+// of its field.
+func Validate(obj interface{}) error {
+	rv := reflect.ValueOf(obj)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	var errs Errors
+	switch rv.Kind() {
+	case reflect.Struct:
+		validateStruct(rv, "", &errs)
+	case reflect.Slice, reflect.Array:
+		validateSlice(rv, "", &errs)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateStruct(rv reflect.Value, ns string, errs *Errors) {
+	if rv.Type() == timeType {
+		return
+	}
+	for _, field := range structRules(rv.Type()) {
+		fv := rv.Field(field.index)
+		name := ns + field.name
+		if field.anonymous {
+			name = ns
+		}
+		if len(field.rules) > 0 {
+			validateField(fv, name, field.rules, errs)
+		}
+		validateNested(fv, name, field.anonymous, errs)
+	}
+}
+
+func validateNested(fv reflect.Value, name string, anonymous bool, errs *Errors) {
+	for fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface {
+		if fv.IsNil() {
+			return
+		}
+		fv = fv.Elem()
+	}
+	switch fv.Kind() {
+	case reflect.Struct:
+		if anonymous {
+			validateStruct(fv, name, errs)
+		} else {
+			validateStruct(fv, name+".", errs)
+		}
+	case reflect.Slice, reflect.Array:
+		validateSlice(fv, name, errs)
+	}
+}
+
+func validateSlice(rv reflect.Value, ns string, errs *Errors) {
+	for i := 0; i < rv.Len(); i++ {
+		ev := rv.Index(i)
+		for ev.Kind() == reflect.Ptr {
+			if ev.IsNil() {
+				break
+			}
+			ev = ev.Elem()
+		}
+		if ev.Kind() == reflect.Struct {
+			validateStruct(ev, ns+"["+strconv.Itoa(i)+"].", errs)
+		}
+	}
+}
+
+func validateField(fv reflect.Value, name string, rules []*rule, errs *Errors) {
+	for _, r := range rules {
+		if r.key != "required" && isZero(fv) {
+			continue
+		}
+		if msg := r.check(indirect(fv), r); msg != "" {
+			*errs = append(*errs, &FieldError{
+				Field:   name,
+				Tag:     r.key,
+				Param:   r.param,
+				Value:   valueOf(fv),
+				Message: name + " " + msg,
+			})
+			return
+		}
+	}
+}
+
+// rule is a parsed rule of a binding tag.
+type rule struct {
+	key   string
+	param string
+	check validatorFunc
+	// bound is the parameter of min, max and len
+	bound float64
+	// re is the parameter of regexp
+	re *regexp.Regexp
+}
+
+// fieldRules are the rules of the field at index of a struct.
+type fieldRules struct {
+	index     int
+	name      string
+	anonymous bool
+	rules     []*rule
+}
+
+// structRulesCache maps the struct types to their []*fieldRules.
+var structRulesCache sync.Map
+
+// structRules returns the fields of rt to validate, parsing their tags on
+// the first call.
+func structRules(rt reflect.Type) []*fieldRules {
+	if fields, ok := structRulesCache.Load(rt); ok {
+		return fields.([]*fieldRules)
+	}
+	var fields []*fieldRules
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		f := &fieldRules{index: i, name: field.Name, anonymous: field.Anonymous}
+		if tag := field.Tag.Get("binding"); tag != "" && tag != "-" {
+			f.rules = parseRules(rt.String()+"."+field.Name, field.Type, tag)
+		}
+		fields = append(fields, f)
+	}
+	structRulesCache.Store(rt, fields)
+	return fields
+}
+
+// parseRules parses the binding tag of the field name of type ft, it panics
+// when the tag is invalid.
+func parseRules(name string, ft reflect.Type, tag string) []*rule {
+	for ft.Kind() == reflect.Ptr {
+		ft = ft.Elem()
+	}
+	var rules []*rule
+	for _, raw := range splitRules(tag) {
+		r := &rule{key: raw}
+		if idx := strings.Index(raw, "="); idx >= 0 {
+			r.key, r.param = raw[:idx], raw[idx+1:]
+		}
+		check, ok := validators[r.key]
+		if !ok {
+			panic("binding: unknown validation rule " + r.key + " on " + name)
+		}
+		r.check = check
+		switch r.key {
+		case "min", "max", "len":
+			if !measurable(ft.Kind()) && ft.Kind() != reflect.Interface {
+				panic("binding: can not compare " + ft.String() + " with " + r.key + " on " + name)
+			}
+			bound, err := strconv.ParseFloat(r.param, 64)
+			if err != nil {
+				panic("binding: bad parameter " + r.param + " of " + r.key + " on " + name)
+			}
+			r.bound = bound
+		case "regexp":
+			re, err := regexp.Compile(r.param)
+			if err != nil {
+				panic("binding: bad regexp " + r.param + " on " + name + ": " + err.Error())
+			}
+			r.re = re
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// splitRules splits the binding tag by commas, everything after regexp= is
+// kept as its parameter.
+func splitRules(tag string) []string {
+	var rules []string
+	for tag != "" {
+		if strings.HasPrefix(tag, "regexp=") {
+			return append(rules, tag)
+		}
+		idx := strings.Index(tag, ",")
+		if idx < 0 {
+			return append(rules, tag)
+		}
+		if idx > 0 {
+			rules = append(rules, tag[:idx])
+		}
+		tag = tag[idx+1:]
+	}
+	return rules
+}
+
+type validatorFunc func(fv reflect.Value, r *rule) string
+
+var validators map[string]validatorFunc
+
+func init() {
+	validators = map[string]validatorFunc{
+		"required": validateRequired,
+		"min":      validateMin,
+		"max":      validateMax,
+		"len":      validateLen,
+		"regexp":   validateRegexp,
+		"oneof":    validateOneOf,
+		"email":    validateEmail,
+	}
+}
+
+func validateRequired(fv reflect.Value, r *rule) string {
+	if isZero(fv) {
+		return "is required"
+	}
+	return ""
+}
+
+func validateMin(fv reflect.Value, r *rule) string {
+	if cmp, ok := compare(fv, r.bound); !ok {
+		return "can not be compared"
+	} else if cmp < 0 {
+		return "must be at least " + r.param
+	}
+	return ""
+}
+
+func validateMax(fv reflect.Value, r *rule) string {
+	if cmp, ok := compare(fv, r.bound); !ok {
+		return "can not be compared"
+	} else if cmp > 0 {
+		return "must be at most " + r.param
+	}
+	return ""
+}
+
+func validateLen(fv reflect.Value, r *rule) string {
+	if cmp, ok := compare(fv, r.bound); !ok {
+		return "can not be compared"
+	} else if cmp != 0 {
+		return "must have length " + r.param
+	}
+	return ""
+}
+
+func validateRegexp(fv reflect.Value, r *rule) string {
+	if fv.Kind() != reflect.String {
+		return "is not a string"
+	}
+	if !r.re.MatchString(fv.String()) {
+		return "must match " + r.param
+	}
+	return ""
+}
+
+func validateOneOf(fv reflect.Value, r *rule) string {
+	// fmt prints the value held by fv even when it can not Interface
+	val := fmt.Sprint(fv)
+	for _, opt := range strings.Fields(r.param) {
+		if val == opt {
+			return ""
+		}
+	}
+	return "must be one of [" + r.param + "]"
+}
+
+var emailRegexp = regexp.MustCompile(`^[a-zA-Z0-9.!#$%&'*+/=?^_{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+
+func validateEmail(fv reflect.Value, r *rule) string {
+	if fv.Kind() != reflect.String || !emailRegexp.MatchString(fv.String()) {
+		return "must be a valid email address"
+	}
+	return ""
+}
+
+// measurable reports whether min, max and len apply to the kind.
+func measurable(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// compare returns -1, 0 or 1 comparing the value of a number, or the length
+// of a string, slice or map, with bound. It is false for the other kinds,
+// which only interfaces can hold as their rules are checked by parseRules.
+func compare(fv reflect.Value, bound float64) (int, bool) {
+	var a float64
+	switch fv.Kind() {
+	case reflect.String:
+		a = float64(utf8.RuneCountInString(fv.String()))
+	case reflect.Slice, reflect.Map, reflect.Array:
+		a = float64(fv.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		a = float64(fv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		a = float64(fv.Uint())
+	case reflect.Float32, reflect.Float64:
+		a = fv.Float()
+	default:
+		return 0, false
+	}
+	switch {
+	case a < bound:
+		return -1, true
+	case a > bound:
+		return 1, true
+	}
+	return 0, true
+}
+
+// isZero does not Interface fv, which may come from an unexported embedded
+// struct.
+func isZero(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return fv.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return fv.IsNil()
+	}
+	return fv.IsZero()
+}
+
+func indirect(fv reflect.Value) reflect.Value {
+	for fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface {
+		if fv.IsNil() {
+			return fv
+		}
+		fv = fv.Elem()
+	}
+	return fv
+}
+
+func valueOf(fv reflect.Value) interface{} {
+	if fv.CanInterface() {
+		return fv.Interface()
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package httpsvr
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrBodyTooLarge is returned by the reads of a request body over its limit.
+var ErrBodyTooLarge = errors.New("httpsvr: request body too large")
+
+// DefaultMaxDecompressed is the default limit of Decompress, 32MB.
+const DefaultMaxDecompressed = 32 << 20
+
+// BodyLimit returns a middleware limiting request bodies to n bytes. Bodies
+// announcing a larger Content-Length get a 413 before the handlers run. For
+// the others the reads past n fail with ErrBodyTooLarge, and the 413 is
+// written once the handlers return unless they wrote a response of their
+// own. Used before Decompress it limits the compressed bytes.
+func BodyLimit(n int64) HandlerFunc {
+	return func(ctx *Context) {
+		if ctx.Req.ContentLength > n {
+			bodyTooLarge(ctx)
+			return
+		}
+		if ctx.Req.Body == nil || ctx.Req.Body == http.NoBody {
+			return
+		}
+		body := &limitedBody{r: ctx.Req.Body, c: ctx.Req.Body, n: n}
+		ctx.Req.Body = body
+		ctx.Next()
+		body.done(ctx)
+	}
+}
+
+// Decompress returns a middleware decoding the request bodies sent with
+// Content-Encoding gzip or deflate, so that the handlers and binding read
+// them as plain. The decoded body is limited to maxSize bytes, 0 stands for
+// DefaultMaxDecompressed, as BodyLimit would. Other encodings get a 415.
+func Decompress(maxSize int64) HandlerFunc {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxDecompressed
+	}
+	return func(ctx *Context) {
+		req := ctx.Req
+		encoding := strings.ToLower(strings.TrimSpace(req.Header.Get(HeaderContentEncoding)))
+		if encoding == "" || encoding == "identity" || req.Body == nil || req.Body == http.NoBody {
+			return
+		}
+
+		var r io.ReadCloser
+		var err error
+		switch encoding {
+		case "gzip", "x-gzip":
+			r, err = gzip.NewReader(req.Body)
+		case "deflate":
+			r, err = newDeflateReader(req.Body)
+		default:
+			ctx.Writer.Header().Set(HeaderAcceptEncoding, "gzip, deflate")
+			ctx.Text(http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+			ctx.Abort()
+			return
+		}
+		if err != nil {
+			ctx.Text("malformed "+encoding+" request body", http.StatusBadRequest)
+			ctx.Abort()
+			return
+		}
+
+		body := &limitedBody{r: r, c: req.Body, dec: r, n: maxSize}
+		req.Body = body
+		req.Header.Del(HeaderContentEncoding)
+		req.Header.Del(HeaderContentLength)
+		req.ContentLength = -1
+		ctx.Next()
+		body.done(ctx)
+	}
+}
+
+// newDeflateReader reads the zlib format as the specification asks, or the
+// raw deflate some clients send instead.
+func newDeflateReader(body io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(body)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// bodyTooLarge answers 413 unless the response is already written.
+func bodyTooLarge(ctx *Context) {
+	if !ctx.Writer.Written() {
+		ctx.Writer.Header().Set("Connection", "close")
+		ctx.Text(http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+	}
+	ctx.Abort()
+}
+
+// limitedBody reads at most n bytes of r and closes c, the original body,
+// and dec, the decoder of Decompress if any.
+type limitedBody struct {
+	r   io.Reader
+	c   io.Closer
+	dec io.Closer
+	n   int64
+	err error
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	// read one byte more than allowed to tell a body of exactly n bytes
+	// from a larger one
+	if int64(len(p)) > b.n+1 {
+		p = p[:b.n+1]
+	}
+	n, err := b.r.Read(p)
+	if int64(n) <= b.n {
+		b.n -= int64(n)
+		b.err = err
+		return n, err
+	}
+	n = int(b.n)
+	b.n = 0
+	b.err = ErrBodyTooLarge
+	return n, b.err
+}
+
+// done answers 413 once the handlers returned if they read past the limit.
+func (b *limitedBody) done(ctx *Context) {
+	if b.err == ErrBodyTooLarge {
+		bodyTooLarge(ctx)
+	}
+}
+
+func (b *limitedBody) Close() error {
+	if b.dec != nil {
+		b.dec.Close()
+	}
+	return b.c.Close()
+}
@@ -0,0 +1,134 @@
+package httpsvr
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_BodyLimit(t *testing.T) {
+	Convey("BodyLimit answers 413 to large bodies", t, func() {
+		var readErr error
+		e := New()
+		e.Use(BodyLimit(10))
+		e.POST("/upload", func(ctx *Context) {
+			body, err := ioutil.ReadAll(ctx.Req.Body)
+			if readErr = err; err != nil {
+				return
+			}
+			ctx.Text(string(body))
+		})
+
+		w := performRequestWith(e, "POST", "/upload", nil, strings.NewReader("0123456789"))
+		So(w.Code, ShouldEqual, 200)
+		So(w.Body.String(), ShouldEqual, "0123456789")
+
+		readErr = nil
+		w = performRequestWith(e, "POST", "/upload", nil, strings.NewReader("0123456789ab"))
+		So(w.Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+		So(readErr, ShouldBeNil)
+
+		// chunked bodies are counted while read
+		w = performRequestWith(e, "POST", "/upload", nil, ioutil.NopCloser(strings.NewReader("0123456789ab")))
+		So(w.Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+		So(readErr, ShouldEqual, ErrBodyTooLarge)
+	})
+
+	Convey("Handlers can answer the reads over the limit themselves", t, func() {
+		var afterRead bool
+		e := New()
+		e.Use(BodyLimit(10))
+		e.POST("/upload", func(ctx *Context) {
+			if _, err := ioutil.ReadAll(ctx.Req.Body); err != nil {
+				afterRead = true
+				ctx.Text("upload at most 10 bytes", http.StatusBadRequest)
+			}
+		})
+
+		w := performRequestWith(e, "POST", "/upload", nil, ioutil.NopCloser(strings.NewReader("0123456789ab")))
+		So(afterRead, ShouldBeTrue)
+		So(w.Code, ShouldEqual, http.StatusBadRequest)
+		So(w.Body.String(), ShouldEqual, "upload at most 10 bytes")
+	})
+}
+
+func Test_Decompress(t *testing.T) {
+	payload := strings.Repeat(`{"name":"bob"}`, 100)
+	compress := func(encoding string, data string) *bytes.Buffer {
+		var b bytes.Buffer
+		var w io.WriteCloser
+		switch encoding {
+		case "gzip":
+			w = gzip.NewWriter(&b)
+		case "deflate":
+			w = zlib.NewWriter(&b)
+		case "raw":
+			w, _ = flate.NewWriter(&b, flate.DefaultCompression)
+		}
+		w.Write([]byte(data))
+		w.Close()
+		return &b
+	}
+
+	Convey("Decompress decodes gzip and deflate request bodies", t, func() {
+		var readErr error
+		e := New()
+		e.Use(BodyLimit(4<<10), Decompress(2000))
+		e.POST("/upload", func(ctx *Context) {
+			body, err := ioutil.ReadAll(ctx.Req.Body)
+			if readErr = err; err != nil {
+				return
+			}
+			ctx.Text(ctx.Req.Header.Get(HeaderContentEncoding) + string(body))
+		})
+
+		for _, encoding := range []string{"gzip", "deflate"} {
+			w := performRequestWith(e, "POST", "/upload", http.Header{HeaderContentEncoding: {encoding}}, compress(encoding, payload))
+			So(w.Code, ShouldEqual, 200)
+			So(w.Body.String(), ShouldEqual, payload)
+		}
+		w := performRequestWith(e, "POST", "/upload", http.Header{HeaderContentEncoding: {"deflate"}}, compress("raw", payload))
+		So(w.Code, ShouldEqual, 200)
+		So(w.Body.String(), ShouldEqual, payload)
+
+		w = performRequestWith(e, "POST", "/upload", nil, strings.NewReader("plain"))
+		So(w.Body.String(), ShouldEqual, "plain")
+
+		So(performRequestWith(e, "POST", "/upload", http.Header{HeaderContentEncoding: {"br"}}, strings.NewReader("x")).Code, ShouldEqual, http.StatusUnsupportedMediaType)
+		So(performRequestWith(e, "POST", "/upload", http.Header{HeaderContentEncoding: {"gzip"}}, strings.NewReader("not gzip")).Code, ShouldEqual, http.StatusBadRequest)
+
+		// a bomb: small on the wire, over the cap once decoded
+		bomb := compress("gzip", strings.Repeat("0", 1<<20))
+		So(bomb.Len(), ShouldBeLessThan, 4<<10)
+		w = performRequestWith(e, "POST", "/upload", http.Header{HeaderContentEncoding: {"gzip"}}, bomb)
+		So(w.Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+		So(readErr, ShouldEqual, ErrBodyTooLarge)
+	})
+
+	Convey("Closing the body closes the decoder too", t, func() {
+		var closed []string
+		body := &limitedBody{
+			r:   strings.NewReader("x"),
+			c:   closeFunc(func() { closed = append(closed, "body") }),
+			dec: closeFunc(func() { closed = append(closed, "decoder") }),
+			n:   1,
+		}
+		So(body.Close(), ShouldBeNil)
+		So(closed, ShouldResemble, []string{"decoder", "body"})
+	})
+}
+
+type closeFunc func()
+
+func (f closeFunc) Close() error {
+	f()
+	return nil
+}
@@ -0,0 +1,331 @@
+package httpsvr
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	lru "github.com/hydah/golib/utils/lru/sync-lru"
+)
+
+const (
+	HeaderCacheControl = "Cache-Control"
+	HeaderAge          = "Age"
+	// HeaderXCache tells whether Cache answered from the store, HIT, or ran
+	// the handlers, MISS.
+	HeaderXCache = "X-Cache"
+
+	// DefaultCacheEntries is the number of responses NewLRUCacheStore keeps
+	// when Cache creates it.
+	DefaultCacheEntries = 1000
+	// DefaultCacheMaxBody is the largest body Cache stores by default, 1MB.
+	DefaultCacheMaxBody = 1 << 20
+)
+
+// CachedResponse is a response stored by Cache.
+type CachedResponse struct {
+	Status  int
+	Header  http.Header
+	Body    []byte
+	Stored  time.Time
+	Expires time.Time
+}
+
+// CacheStore stores the responses of Cache. Get returns no expired
+// responses.
+type CacheStore interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, resp *CachedResponse)
+	Delete(key string)
+}
+
+// CacheOptions configures Cache.
+type CacheOptions struct {
+	// TTL of the stored responses, default a minute.
+	TTL time.Duration
+	// Store holds the responses, default an LRU of DefaultCacheEntries.
+	Store CacheStore
+	// QueryParams are the query parameters which tell responses apart, the
+	// others are ignored.
+	QueryParams []string
+	// Headers are the request headers which tell responses apart, e.g.
+	// "Accept-Language". Responses varying, with Vary, on other headers are
+	// not stored.
+	Headers []string
+	// MaxBody is the largest body stored, default DefaultCacheMaxBody.
+	MaxBody int
+}
+
+// Cache returns a middleware storing the 200 responses to GET requests and
+// answering the next GET and HEAD requests of the same path, query
+// parameters and headers with them until TTL. Concurrent misses of the same
+// key run the handlers once, the others wait for the response.
+//
+// Callers sending Cache-Control no-cache skip the stored response and store
+// a fresh one, no-store skips the cache altogether. Responses setting
+// cookies or Cache-Control no-store, no-cache or private are not stored,
+// nor the ones with a Vary header naming headers which are not in Headers.
+func Cache(opts CacheOptions) HandlerFunc {
+	if opts.TTL <= 0 {
+		opts.TTL = time.Minute
+	}
+	if opts.Store == nil {
+		opts.Store = NewLRUCacheStore(DefaultCacheEntries)
+	}
+	if opts.MaxBody <= 0 {
+		opts.MaxBody = DefaultCacheMaxBody
+	}
+	var lock sync.Mutex
+	calls := make(map[string]*cacheCall)
+
+	return func(ctx *Context) {
+		method := ctx.Req.Method
+		if method != "GET" && method != "HEAD" {
+			return
+		}
+		directives := ctx.Req.Header.Get(HeaderCacheControl)
+		if hasDirective(directives, "no-store") {
+			return
+		}
+		noCache := hasDirective(directives, "no-cache") || ctx.Req.Header.Get("Pragma") == "no-cache"
+		key := cacheKey(ctx.Req, &opts)
+
+		if !noCache {
+			if resp, ok := opts.Store.Get(key); ok {
+				serveCached(ctx, resp)
+				return
+			}
+		}
+		if method == "HEAD" {
+			return
+		}
+
+		var call *cacheCall
+		if !noCache {
+			lock.Lock()
+			if leader, ok := calls[key]; ok {
+				lock.Unlock()
+				<-leader.done
+				if leader.resp != nil {
+					serveCached(ctx, leader.resp)
+				}
+				// else the response could not be stored, run the handlers
+				return
+			}
+			call = &cacheCall{done: make(chan struct{})}
+			calls[key] = call
+			lock.Unlock()
+			defer func() {
+				lock.Lock()
+				delete(calls, key)
+				lock.Unlock()
+				close(call.done)
+			}()
+		}
+
+		ctx.Writer.Header().Set(HeaderXCache, "MISS")
+		// the headers set before, e.g. by RequestID, are not the handlers'
+		before := cloneHeader(ctx.Writer.Header())
+		cw := &cacheWriter{ResponseWriter: ctx.Writer, max: opts.MaxBody}
+		ctx.Writer = cw
+		ctx.Next()
+		ctx.Writer = cw.ResponseWriter
+
+		header := cw.sent()
+		if !cw.storable() || !varyCovered(before, header, opts.Headers) {
+			return
+		}
+		now := time.Now()
+		resp := &CachedResponse{
+			Status:  cw.Status(),
+			Header:  headerSince(before, header),
+			Body:    cw.body.Bytes(),
+			Stored:  now,
+			Expires: now.Add(opts.TTL),
+		}
+		opts.Store.Set(key, resp)
+		if call != nil {
+			call.resp = resp
+		}
+	}
+}
+
+type cacheCall struct {
+	done chan struct{}
+	resp *CachedResponse
+}
+
+func cacheKey(req *http.Request, opts *CacheOptions) string {
+	var b strings.Builder
+	b.WriteString(req.URL.Path)
+	if len(opts.QueryParams) > 0 {
+		query := req.URL.Query()
+		for _, name := range opts.QueryParams {
+			b.WriteString("\x00" + name + "=" + strings.Join(query[name], ","))
+		}
+	}
+	for _, name := range opts.Headers {
+		b.WriteString("\x00" + name + ":" + strings.Join(req.Header[http.CanonicalHeaderKey(name)], ","))
+	}
+	return b.String()
+}
+
+// headerSince returns the headers of after which are not in before.
+func headerSince(before, after http.Header) http.Header {
+	h := make(http.Header)
+	for k, v := range after {
+		if strings.Join(before[k], "\x00") != strings.Join(v, "\x00") {
+			h[k] = append([]string(nil), v...)
+		}
+	}
+	return h
+}
+
+// hasDirective reports whether a Cache-Control header holds the directive.
+func hasDirective(header, directive string) bool {
+	for _, d := range strings.Split(header, ",") {
+		d = strings.TrimSpace(d)
+		if i := strings.IndexByte(d, '='); i >= 0 {
+			d = d[:i]
+		}
+		if strings.EqualFold(d, directive) {
+			return true
+		}
+	}
+	return false
+}
+
+func serveCached(ctx *Context, resp *CachedResponse) {
+	header := ctx.Writer.Header()
+	for k, v := range resp.Header {
+		header[k] = append([]string(nil), v...)
+	}
+	header.Set(HeaderXCache, "HIT")
+	header.Set(HeaderAge, strconv.Itoa(int(time.Since(resp.Stored)/time.Second)))
+	ctx.Writer.WriteHeader(resp.Status)
+	if ctx.Req.Method == "HEAD" {
+		ctx.Writer.WriteHeaderNow()
+	} else {
+		ctx.Writer.Write(resp.Body)
+	}
+	ctx.Abort()
+}
+
+// cacheWriter keeps a copy of the body written through it, and of the
+// headers as they were when the handlers started writing: those added later
+// by the middlewares running ahead of Cache, e.g. the Content-Encoding of
+// Compress, do not describe the stored body.
+type cacheWriter struct {
+	ResponseWriter
+	body     bytes.Buffer
+	header   http.Header
+	max      int
+	tooLarge bool
+}
+
+// sent returns the headers of the handlers' response.
+func (w *cacheWriter) sent() http.Header {
+	if w.header == nil {
+		w.header = cloneHeader(w.Header())
+	}
+	return w.header
+}
+
+func (w *cacheWriter) WriteHeaderNow() {
+	w.sent()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *cacheWriter) Flush() {
+	w.sent()
+	w.ResponseWriter.Flush()
+}
+
+func (w *cacheWriter) Write(data []byte) (int, error) {
+	w.sent()
+	if !w.tooLarge {
+		if w.body.Len()+len(data) > w.max {
+			w.tooLarge = true
+			w.body = bytes.Buffer{}
+		} else {
+			w.body.Write(data)
+		}
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *cacheWriter) storable() bool {
+	if w.tooLarge || w.Status() != http.StatusOK {
+		return false
+	}
+	header := w.Header()
+	if header.Get("Set-Cookie") != "" {
+		return false
+	}
+	directives := header.Get(HeaderCacheControl)
+	return !hasDirective(directives, "no-store") && !hasDirective(directives, "no-cache") &&
+		!hasDirective(directives, "private")
+}
+
+// varyCovered reports whether the headers named in the Vary of the handlers
+// are all part of the key, never for Vary *. The names set before, e.g. the
+// Accept-Encoding of Compress running ahead of Cache, are not stored and
+// are set again on the hits.
+func varyCovered(before, after http.Header, keyed []string) bool {
+	set := make(map[string]bool)
+	for _, v := range before[HeaderVary] {
+		for _, name := range strings.Split(v, ",") {
+			set[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+		}
+	}
+	for _, v := range after[HeaderVary] {
+		for _, name := range strings.Split(v, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "*" {
+				return false
+			}
+			if name != "" && !set[name] && !containsFold(keyed, name) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+type lruCacheStore struct {
+	cache *lru.Cache
+}
+
+// NewLRUCacheStore returns a CacheStore keeping up to size responses in
+// memory, the least recently used go first.
+func NewLRUCacheStore(size int) CacheStore {
+	cache, err := lru.New(size)
+	if err != nil {
+		panic(err)
+	}
+	return &lruCacheStore{cache: cache}
+}
+
+func (s *lruCacheStore) Get(key string) (*CachedResponse, bool) {
+	v, ok := s.cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	resp := v.(*CachedResponse)
+	if !time.Now().Before(resp.Expires) {
+		s.cache.Remove(key)
+		return nil, false
+	}
+	return resp, true
+}
+
+func (s *lruCacheStore) Set(key string, resp *CachedResponse) {
+	s.cache.Add(key, resp)
+}
+
+func (s *lruCacheStore) Delete(key string) {
+	s.cache.Remove(key)
+}
@@ -0,0 +1,155 @@
+package httpsvr
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_Cache(t *testing.T) {
+	Convey("Cache stores responses by path, query params and headers", t, func() {
+		var runs int32
+		e := New()
+		e.Use(Cache(CacheOptions{TTL: 50 * time.Millisecond, QueryParams: []string{"page"}, Headers: []string{"Accept-Language"}}))
+		e.GET("/items", func(ctx *Context) {
+			n := atomic.AddInt32(&runs, 1)
+			ctx.SetHeader("X-Run", strconv.Itoa(int(n)))
+			ctx.Text("page " + ctx.Req.URL.Query().Get("page"))
+		})
+		e.GET("/private", func(ctx *Context) {
+			atomic.AddInt32(&runs, 1)
+			ctx.SetHeader(HeaderCacheControl, "private")
+			ctx.Text("mine")
+		})
+		e.GET("/vary/:name", func(ctx *Context) {
+			atomic.AddInt32(&runs, 1)
+			ctx.Writer.Header().Add(HeaderVary, strings.TrimPrefix(ctx.Req.URL.Path, "/vary/"))
+			ctx.Text("varies")
+		})
+
+		w := performRequest(e, "GET", "/items?page=1")
+		So(w.Header().Get(HeaderXCache), ShouldEqual, "MISS")
+		w = performRequest(e, "GET", "/items?page=1&utm=x")
+		So(w.Header().Get(HeaderXCache), ShouldEqual, "HIT")
+		So(w.Header().Get("X-Run"), ShouldEqual, "1")
+		So(w.Body.String(), ShouldEqual, "page 1")
+		So(atomic.LoadInt32(&runs), ShouldEqual, 1)
+
+		w = performRequest(e, "HEAD", "/items?page=1")
+		So(w.Header().Get(HeaderXCache), ShouldEqual, "HIT")
+		So(w.Body.Len(), ShouldEqual, 0)
+
+		So(performRequest(e, "GET", "/items?page=2").Body.String(), ShouldEqual, "page 2")
+		So(performRequestWith(e, "GET", "/items?page=1", http.Header{"Accept-Language": {"fr"}}, nil).Header().Get(HeaderXCache), ShouldEqual, "MISS")
+		So(atomic.LoadInt32(&runs), ShouldEqual, 3)
+
+		w = performRequestWith(e, "GET", "/items?page=1", http.Header{HeaderCacheControl: {"no-cache"}}, nil)
+		So(w.Header().Get(HeaderXCache), ShouldEqual, "MISS")
+		So(performRequest(e, "GET", "/items?page=1").Header().Get("X-Run"), ShouldEqual, "4")
+
+		time.Sleep(60 * time.Millisecond)
+		So(performRequest(e, "GET", "/items?page=1").Header().Get(HeaderXCache), ShouldEqual, "MISS")
+
+		performRequest(e, "GET", "/private")
+		So(performRequest(e, "GET", "/private").Header().Get(HeaderXCache), ShouldEqual, "MISS")
+
+		// only the responses varying on the keyed headers are stored
+		for vary, cached := range map[string]string{"accept-language": "HIT", "Accept-Encoding": "MISS", "*": "MISS"} {
+			performRequest(e, "GET", "/vary/"+vary)
+			So(performRequest(e, "GET", "/vary/"+vary).Header().Get(HeaderXCache), ShouldEqual, cached)
+		}
+	})
+
+	Convey("Cache stores what the handlers wrote around Compress", t, func() {
+		body := strings.Repeat("x", 1900)
+		gzipped := http.Header{HeaderAcceptEncoding: {"gzip"}}
+		decode := func(w *httptest.ResponseRecorder) string {
+			if w.Header().Get(HeaderContentEncoding) != EncodingGzip {
+				return w.Body.String()
+			}
+			gz, err := gzip.NewReader(w.Body)
+			So(err, ShouldBeNil)
+			data, _ := ioutil.ReadAll(gz)
+			return string(data)
+		}
+
+		// Compress ahead of Cache: the plain body is stored and compressed
+		// again for each client
+		e := New()
+		e.Use(Compress(CompressOptions{}), Cache(CacheOptions{}))
+		e.GET("/long", func(ctx *Context) { ctx.Text(body) })
+		w := performRequestWith(e, "GET", "/long", gzipped, nil)
+		So(w.Header().Get(HeaderContentEncoding), ShouldEqual, EncodingGzip)
+		So(decode(w), ShouldEqual, body)
+		w = performRequestWith(e, "GET", "/long", gzipped, nil)
+		So(w.Header().Get(HeaderXCache), ShouldEqual, "HIT")
+		So(w.Header().Get(HeaderContentEncoding), ShouldEqual, EncodingGzip)
+		So(decode(w), ShouldEqual, body)
+		w = performRequest(e, "GET", "/long")
+		So(w.Header().Get(HeaderXCache), ShouldEqual, "HIT")
+		So(w.Header().Get(HeaderContentEncoding), ShouldEqual, "")
+		So(w.Body.String(), ShouldEqual, body)
+
+		// Cache ahead of Compress: the encoded body is stored, by
+		// Accept-Encoding only when it is part of the key
+		for _, headers := range [][]string{nil, {HeaderAcceptEncoding}} {
+			e := New()
+			e.Use(Cache(CacheOptions{Headers: headers}), Compress(CompressOptions{}))
+			e.GET("/long", func(ctx *Context) { ctx.Text(body) })
+			performRequestWith(e, "GET", "/long", gzipped, nil)
+			w := performRequestWith(e, "GET", "/long", gzipped, nil)
+			So(w.Header().Get(HeaderContentEncoding), ShouldEqual, EncodingGzip)
+			So(decode(w), ShouldEqual, body)
+			w = performRequest(e, "GET", "/long")
+			So(w.Header().Get(HeaderXCache), ShouldEqual, "MISS")
+			So(w.Header().Get(HeaderContentEncoding), ShouldEqual, "")
+			So(w.Body.String(), ShouldEqual, body)
+			if headers == nil {
+				So(performRequestWith(e, "GET", "/long", gzipped, nil).Header().Get(HeaderXCache), ShouldEqual, "MISS")
+			} else {
+				So(performRequest(e, "GET", "/long").Header().Get(HeaderXCache), ShouldEqual, "HIT")
+			}
+		}
+	})
+
+	Convey("Concurrent misses run the handlers once", t, func() {
+		var runs int32
+		release := make(chan struct{})
+		e := New()
+		e.Use(Cache(CacheOptions{}))
+		e.GET("/slow", func(ctx *Context) {
+			atomic.AddInt32(&runs, 1)
+			<-release
+			ctx.Text("done")
+		})
+
+		var wg sync.WaitGroup
+		bodies := make([]string, 10)
+		for i := range bodies {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest("GET", "/slow", nil)
+				e.ServeHTTP(w, req)
+				bodies[i] = w.Body.String()
+			}(i)
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		So(atomic.LoadInt32(&runs), ShouldEqual, 1)
+		for _, body := range bodies {
+			So(body, ShouldEqual, "done")
+		}
+	})
+}
@@ -23,8 +23,15 @@ type Context struct {
 	Engine      *Engine
 	writer      writer
 	handlers    []HandlerFunc
-	controllers []reflect.Type
+	controllers []*controllerPool
 	index       int8
+	route       string
+	// detached is set when handlers may still run on the context after the
+	// request, see Timeout
+	detached bool
+	// underTimeout is set on the copies running the handlers under Timeout,
+	// which can not hijack the connection
+	underTimeout bool
 	HtmlEngine
 }
 
@@ -39,41 +46,51 @@ func (c *Context) Next() {
 	s := int8(len(c.handlers))
 	for ; c.index < s; c.index++ {
 		if len(c.controllers) > 0 && c.controllers[c.index] != nil {
-			ctrl, ok := reflect.New(c.controllers[c.index]).Interface().(IController)
-			if !ok {
-				logger.Error("controller is not IController, %v", c.controllers[c.index].Name())
-				return
-			}
-			ctrl.InitCtx(c)
-			ctrl.InitBase(c)
-			ctrl.InitApp(c)
-
-			if ctrl.Prepare(c) {
-				switch c.Req.Method {
-				case "GET":
-					ctrl.Get(c)
-				case "POST":
-					ctrl.Post(c)
-				case "PATCH":
-					ctrl.Patch(c)
-				case "PUT":
-					ctrl.Put(c)
-				case "OPTIONS":
-					ctrl.Options(c)
-				case "HEAD":
-					ctrl.Head(c)
-				default:
-					logger.Error("method: %s, controller handler Not Implemented, %v", c.Req.Method, c.controllers[c.index].Name())
-				}
-			}
-			ctrl.Finish(c)
-
+			runController(c, c.controllers[c.index], dispatchMethod)
 		} else {
 			c.handlers[c.index](c)
 		}
 	}
 }
 
+// runController runs action on a controller of p between its lifecycle
+// hooks.
+func runController(c *Context, p *controllerPool, action func(IController, *Context)) {
+	ctrl := p.get()
+	ctrl.InitCtx(c)
+	ctrl.InitBase(c)
+	ctrl.InitApp(c)
+	if ctrl.Prepare(c) {
+		action(ctrl, c)
+	}
+	ctrl.Finish(c)
+	p.put(ctrl)
+}
+
+// dispatchMethod calls the controller method of the request method.
+func dispatchMethod(ctrl IController, c *Context) {
+	switch c.Req.Method {
+	case "GET":
+		ctrl.Get(c)
+	case "POST":
+		ctrl.Post(c)
+	case "DELETE":
+		ctrl.Delete(c)
+	case "PATCH":
+		ctrl.Patch(c)
+	case "PUT":
+		ctrl.Put(c)
+	case "OPTIONS":
+		ctrl.Options(c)
+	case "HEAD":
+		ctrl.Head(c)
+	default:
+		logger.Error("method: %s, controller handler Not Implemented, %v", c.Req.Method, reflect.TypeOf(ctrl).Elem().Name())
+		c.Writer.WriteHeader(http.StatusMethodNotAllowed)
+		c.Abort()
+	}
+}
+
 // Sets a new pair key/value just for the specified context.
 func (c *Context) Set(key string, item interface{}) {
 	if c.Keys == nil {
@@ -190,6 +207,12 @@ func (c *Context) Proxy() (proxy []string) {
 	return []string{}
 }
 
+// Route returns the pattern of the matched route, e.g. "/users/:id", or ""
+// when no route matched.
+func (c *Context) Route() string {
+	return c.route
+}
+
 // UserAgent _
 func (c *Context) UserAgent() string {
 	return c.Req.Header.Get("User-Agent")
@@ -205,19 +228,25 @@ func (c *Context) executeRender(data interface{}, w http.ResponseWriter, render
 	}
 }
 
-func (c *Engine) createContext(w http.ResponseWriter, req *http.Request, params httprouter.Params, handlers []HandlerFunc, controllers []reflect.Type) *Context {
+func (c *Engine) createContext(w http.ResponseWriter, req *http.Request, params httprouter.Params, handlers []HandlerFunc, controllers []*controllerPool) *Context {
 	ctx := c.pool.Get().(*Context)
 	ctx.Writer = &ctx.writer
 	ctx.Req = req
 	ctx.Keys = nil
+	ctx.Session = nil
 	ctx.Params = params
 	ctx.handlers = handlers
 	ctx.controllers = controllers
+	ctx.route = ""
+	ctx.detached = false
 	ctx.writer.reset(w)
 	ctx.index = -1
 	return ctx
 }
 
 func (c *Engine) reuseContext(ctx *Context) {
+	if ctx.detached {
+		return
+	}
 	c.pool.Put(ctx)
 }
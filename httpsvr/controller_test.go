@@ -0,0 +1,160 @@
+package httpsvr
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type store struct {
+	name string
+}
+
+type storeController struct {
+	Controller
+	Store *store
+	hits  int
+}
+
+func (c *storeController) Get(ctx *Context) {
+	c.hits++
+	ctx.Text(c.Store.name + " " + strconv.Itoa(c.hits))
+}
+
+type zeroController struct {
+	Controller
+	hits int
+}
+
+func (c *zeroController) Get(ctx *Context) {
+	c.hits++
+	ctx.Text(strconv.Itoa(c.hits))
+}
+
+type resettableController struct {
+	storeController
+	resets *int
+}
+
+func (c *resettableController) Reset() {
+	*c.resets++
+	c.hits = 0
+}
+
+func Test_ControllerPool(t *testing.T) {
+	get := func(e *Engine, path string) string {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		e.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	Convey("Controllers are zero values of the registered type", t, func() {
+		e := New()
+		e.GETController("/", &zeroController{hits: 7})
+		So(get(e, "/"), ShouldEqual, "1")
+		So(get(e, "/"), ShouldEqual, "1")
+	})
+
+	Convey("Prototype controllers are copies of the registered one", t, func() {
+		e := New()
+		ctrl := &storeController{Store: &store{"db"}}
+		e.HandleControllerPrototype("GET", "/", ctrl, ctrl.Get)
+		So(get(e, "/"), ShouldEqual, "db 1")
+		So(get(e, "/"), ShouldEqual, "db 1")
+		So(ctrl.hits, ShouldEqual, 0)
+	})
+
+	Convey("Factories make the controllers", t, func() {
+		e := New()
+		made := 0
+		e.HandleControllerFunc("GET", "/", func() IController {
+			made++
+			return &storeController{Store: &store{"factory"}}
+		})
+		So(get(e, "/"), ShouldEqual, "factory 1")
+		So(get(e, "/"), ShouldEqual, "factory 1")
+		So(made, ShouldBeGreaterThan, 2)
+		So(e.Routes()[0].Handler, ShouldEndWith, "Get-fm")
+	})
+
+	Convey("Resetters are reused", t, func() {
+		e := New()
+		resets := 0
+		e.HandleControllerFunc("GET", "/", func() IController {
+			return &resettableController{storeController{Store: &store{"pooled"}}, &resets}
+		})
+		So(get(e, "/"), ShouldEqual, "pooled 1")
+		So(get(e, "/"), ShouldEqual, "pooled 1")
+		So(resets, ShouldBeGreaterThanOrEqualTo, 2)
+	})
+
+	Convey("Prototypes must be pointers to structs", t, func() {
+		So(func() { newPrototypePool(nil) }, ShouldPanic)
+	})
+}
+
+// reflectController emulates the instantiation of the controllers before
+// the pools.
+func reflectController(ctrl IController) HandlerFunc {
+	t := reflect.TypeOf(ctrl).Elem()
+	return func(ctx *Context) {
+		c := reflect.New(t).Interface().(IController)
+		c.InitCtx(ctx)
+		c.InitBase(ctx)
+		c.InitApp(ctx)
+		if c.Prepare(ctx) {
+			c.Get(ctx)
+		}
+		c.Finish(ctx)
+	}
+}
+
+type benchController struct {
+	Controller
+	Store *store
+	Attrs map[string]string
+}
+
+func (c *benchController) Get(ctx *Context) {
+	ctx.Writer.WriteHeader(http.StatusNoContent)
+}
+
+func (c *benchController) Reset() {
+	c.Attrs = nil
+}
+
+func benchmarkController(b *testing.B, e *Engine) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkControllerReflect(b *testing.B) {
+	e := New()
+	e.GET("/", reflectController(&benchController{}))
+	benchmarkController(b, e)
+}
+
+func BenchmarkControllerPrototype(b *testing.B) {
+	e := New()
+	e.GETController("/", &benchController{Store: &store{"db"}})
+	benchmarkController(b, e)
+}
+
+func BenchmarkControllerFactory(b *testing.B) {
+	e := New()
+	db := &store{"db"}
+	e.HandleControllerFunc("GET", "/", func() IController {
+		return &benchController{Store: db}
+	})
+	benchmarkController(b, e)
+}
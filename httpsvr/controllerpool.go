@@ -0,0 +1,89 @@
+package httpsvr
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ControllerFactory returns a new controller, e.g. one holding the
+// dependencies of its handlers:
+//
+//	api.HandleControllerFunc("GET", "/users/:id", func() IController {
+//		return &UsersController{DB: db}
+//	})
+type ControllerFactory func() IController
+
+// Resetter is implemented by the controllers which are reused between
+// requests, Reset clears the state of a request, after Finish. A controller
+// must not be used once it is reset.
+type Resetter interface {
+	Reset()
+}
+
+// controllerPool reuses the controllers of a route.
+type controllerPool struct {
+	pool sync.Pool
+	// reset prepares a controller for the next request, it is false if the
+	// controller can not be reused.
+	reset func(ctrl IController) bool
+}
+
+// newFactoryPool pools the controllers of factory which are Resetters, the
+// others are made for every request.
+func newFactoryPool(factory ControllerFactory) *controllerPool {
+	p := &controllerPool{reset: resetController}
+	p.pool.New = func() interface{} { return factory() }
+	return p
+}
+
+// newZeroPool makes a zero value of the type ctrl points to for every
+// request, the Resetters are reused.
+func newZeroPool(ctrl IController) *controllerPool {
+	t := reflect.TypeOf(ctrl).Elem()
+	return newFactoryPool(func() IController {
+		return reflect.New(t).Interface().(IController)
+	})
+}
+
+// newPrototypePool pools copies of the controller ctrl points to, the fields
+// set on ctrl are shared by the copies. They are reset with Reset, or with
+// a copy of ctrl when they are no Resetters.
+func newPrototypePool(ctrl IController) *controllerPool {
+	proto := reflect.ValueOf(ctrl)
+	if proto.Kind() != reflect.Ptr || proto.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("httpsvr: controller %T is not a pointer to a struct", ctrl))
+	}
+	proto = reflect.Indirect(proto)
+	p := &controllerPool{}
+	p.pool.New = func() interface{} {
+		v := reflect.New(proto.Type())
+		v.Elem().Set(proto)
+		return v.Interface()
+	}
+	p.reset = func(ctrl IController) bool {
+		if !resetController(ctrl) {
+			reflect.ValueOf(ctrl).Elem().Set(proto)
+		}
+		return true
+	}
+	return p
+}
+
+func resetController(ctrl IController) bool {
+	r, ok := ctrl.(Resetter)
+	if ok {
+		r.Reset()
+	}
+	return ok
+}
+
+func (p *controllerPool) get() IController {
+	return p.pool.Get().(IController)
+}
+
+func (p *controllerPool) put(ctrl IController) {
+	if p.reset(ctrl) {
+		p.pool.Put(ctrl)
+	}
+}
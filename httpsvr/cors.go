@@ -0,0 +1,152 @@
+package httpsvr
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderOrigin                        = "Origin"
+	HeaderAccessControlRequestMethod    = "Access-Control-Request-Method"
+	HeaderAccessControlRequestHeaders   = "Access-Control-Request-Headers"
+	HeaderAccessControlAllowOrigin      = "Access-Control-Allow-Origin"
+	HeaderAccessControlAllowMethods     = "Access-Control-Allow-Methods"
+	HeaderAccessControlAllowHeaders     = "Access-Control-Allow-Headers"
+	HeaderAccessControlAllowCredentials = "Access-Control-Allow-Credentials"
+	HeaderAccessControlExposeHeaders    = "Access-Control-Expose-Headers"
+	HeaderAccessControlMaxAge           = "Access-Control-Max-Age"
+)
+
+// CORSOptions configures CORS.
+type CORSOptions struct {
+	// AllowOrigins are the allowed origins, "*" allows any origin and a
+	// "*" inside an origin matches any subdomain, e.g.
+	// "https://*.example.com".
+	AllowOrigins []string
+	// AllowOriginFunc allows the origins it returns true for, in addition
+	// to AllowOrigins.
+	AllowOriginFunc func(origin string) bool
+	// AllowMethods default to GET, HEAD, POST, PUT, PATCH and DELETE.
+	AllowMethods []string
+	// AllowHeaders are the request headers allowed in preflight requests,
+	// default the ones the request asks for.
+	AllowHeaders []string
+	// ExposeHeaders are the response headers the browser exposes.
+	ExposeHeaders []string
+	// AllowCredentials allows cookies and authorization headers, the origin
+	// is then echoed instead of "*".
+	AllowCredentials bool
+	// MaxAge is how long the browser caches a preflight response.
+	MaxAge time.Duration
+}
+
+// CORS returns a middleware implementing cross-origin resource sharing.
+// It answers preflight requests with a 204 and aborts, including those for
+// paths registered with other methods only, as long as it is used on the
+// Engine rather than on a RouterGroup. Preflight requests from a disallowed
+// origin or for a disallowed method get a 403; other requests from a
+// disallowed origin go through without CORS headers.
+func CORS(opts CORSOptions) HandlerFunc {
+	if len(opts.AllowMethods) == 0 {
+		opts.AllowMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+	}
+	allowAll := false
+	for _, origin := range opts.AllowOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+	}
+	methods := strings.Join(opts.AllowMethods, ", ")
+	headers := strings.Join(opts.AllowHeaders, ", ")
+	expose := strings.Join(opts.ExposeHeaders, ", ")
+	maxAge := strconv.Itoa(int(opts.MaxAge / time.Second))
+
+	allowed := func(origin string) bool {
+		if allowAll {
+			return true
+		}
+		for _, pattern := range opts.AllowOrigins {
+			if matchOrigin(pattern, origin) {
+				return true
+			}
+		}
+		return opts.AllowOriginFunc != nil && opts.AllowOriginFunc(origin)
+	}
+
+	return func(ctx *Context) {
+		origin := ctx.Req.Header.Get(HeaderOrigin)
+		if origin == "" {
+			return
+		}
+		h := ctx.Writer.Header()
+		preflight := ctx.Req.Method == "OPTIONS" && ctx.Req.Header.Get(HeaderAccessControlRequestMethod) != ""
+		if !allowAll || opts.AllowCredentials {
+			h.Add(HeaderVary, HeaderOrigin)
+		}
+		if !allowed(origin) {
+			if preflight {
+				ctx.Writer.WriteHeader(http.StatusForbidden)
+				ctx.Abort()
+			}
+			return
+		}
+
+		if allowAll && !opts.AllowCredentials {
+			h.Set(HeaderAccessControlAllowOrigin, "*")
+		} else {
+			h.Set(HeaderAccessControlAllowOrigin, origin)
+		}
+		if opts.AllowCredentials {
+			h.Set(HeaderAccessControlAllowCredentials, "true")
+		}
+		if !preflight {
+			if expose != "" {
+				h.Set(HeaderAccessControlExposeHeaders, expose)
+			}
+			return
+		}
+
+		if !containsFold(opts.AllowMethods, ctx.Req.Header.Get(HeaderAccessControlRequestMethod)) {
+			ctx.Writer.WriteHeader(http.StatusForbidden)
+			ctx.Abort()
+			return
+		}
+		h.Set(HeaderAccessControlAllowMethods, methods)
+		if headers != "" {
+			h.Set(HeaderAccessControlAllowHeaders, headers)
+		} else if requested := ctx.Req.Header.Get(HeaderAccessControlRequestHeaders); requested != "" {
+			h.Add(HeaderVary, HeaderAccessControlRequestHeaders)
+			h.Set(HeaderAccessControlAllowHeaders, requested)
+		}
+		if opts.MaxAge > 0 {
+			h.Set(HeaderAccessControlMaxAge, maxAge)
+		}
+		h.Del("Allow")
+		ctx.Writer.WriteHeader(http.StatusNoContent)
+		ctx.Abort()
+	}
+}
+
+// matchOrigin matches origin against pattern, a "*" in pattern matches a
+// non empty part of the host.
+func matchOrigin(pattern, origin string) bool {
+	pattern, origin = strings.ToLower(pattern), strings.ToLower(origin)
+	idx := strings.Index(pattern, "*")
+	if idx < 0 {
+		return pattern == origin
+	}
+	prefix, suffix := pattern[:idx], pattern[idx+1:]
+	return len(origin) > len(prefix)+len(suffix) &&
+		strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package httpsvr
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_CORS(t *testing.T) {
+	e := New()
+	e.Use(CORS(CORSOptions{
+		AllowOrigins:     []string{"https://app.example.com", "https://*.example.org"},
+		AllowOriginFunc:  func(origin string) bool { return strings.HasSuffix(origin, ".local") },
+		AllowMethods:     []string{"GET", "POST"},
+		ExposeHeaders:    []string{"X-Total"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}))
+	e.GET("/users", func(ctx *Context) { ctx.Text("users") })
+
+	Convey("Preflight requests are answered on a GET only path", t, func() {
+		w := performRequestWith(e, "OPTIONS", "/users", http.Header{
+			HeaderOrigin:                      {"https://api.example.org"},
+			HeaderAccessControlRequestMethod:  {"POST"},
+			HeaderAccessControlRequestHeaders: {"Content-Type, X-Token"},
+		}, nil)
+		So(w.Code, ShouldEqual, http.StatusNoContent)
+		So(w.Header().Get(HeaderAccessControlAllowOrigin), ShouldEqual, "https://api.example.org")
+		So(w.Header().Get(HeaderAccessControlAllowMethods), ShouldEqual, "GET, POST")
+		So(w.Header().Get(HeaderAccessControlAllowHeaders), ShouldEqual, "Content-Type, X-Token")
+		So(w.Header().Get(HeaderAccessControlAllowCredentials), ShouldEqual, "true")
+		So(w.Header().Get(HeaderAccessControlMaxAge), ShouldEqual, "600")
+		So(w.Header()[HeaderVary], ShouldResemble, []string{HeaderOrigin, HeaderAccessControlRequestHeaders})
+		So(w.Body.String(), ShouldEqual, "")
+
+		So(performRequestWith(e, "OPTIONS", "/users", http.Header{HeaderOrigin: {"https://evil.com"}, HeaderAccessControlRequestMethod: {"GET"}}, nil).Code, ShouldEqual, http.StatusForbidden)
+		So(performRequestWith(e, "OPTIONS", "/users", http.Header{HeaderOrigin: {"https://app.example.com"}, HeaderAccessControlRequestMethod: {"DELETE"}}, nil).Code, ShouldEqual, http.StatusForbidden)
+		So(performRequestWith(e, "OPTIONS", "/users", http.Header{HeaderOrigin: {"http://dev.local"}, HeaderAccessControlRequestMethod: {"GET"}}, nil).Code, ShouldEqual, http.StatusNoContent)
+	})
+
+	Convey("Actual requests get the allow and expose headers", t, func() {
+		w := performRequestWith(e, "GET", "/users", http.Header{HeaderOrigin: {"https://app.example.com"}}, nil)
+		So(w.Code, ShouldEqual, 200)
+		So(w.Body.String(), ShouldEqual, "users")
+		So(w.Header().Get(HeaderAccessControlAllowOrigin), ShouldEqual, "https://app.example.com")
+		So(w.Header().Get(HeaderAccessControlExposeHeaders), ShouldEqual, "X-Total")
+
+		w = performRequestWith(e, "GET", "/users", http.Header{HeaderOrigin: {"https://evil.com"}}, nil)
+		So(w.Code, ShouldEqual, 200)
+		So(w.Header().Get(HeaderAccessControlAllowOrigin), ShouldEqual, "")
+
+		w = performRequest(e, "GET", "/users")
+		So(w.Header().Get(HeaderVary), ShouldEqual, "")
+	})
+
+	Convey("Other methods still get a 405 with Allow", t, func() {
+		w := performRequestWith(e, "DELETE", "/users", http.Header{HeaderOrigin: {"https://app.example.com"}}, nil)
+		So(w.Code, ShouldEqual, http.StatusMethodNotAllowed)
+		So(w.Header().Get("Allow"), ShouldEqual, "GET")
+
+		w = performRequest(e, "OPTIONS", "/users")
+		So(w.Code, ShouldEqual, http.StatusMethodNotAllowed)
+	})
+
+	Convey("A wildcard origin without credentials allows any origin", t, func() {
+		e := New()
+		e.Use(CORS(CORSOptions{AllowOrigins: []string{"*"}}))
+		e.POST("/items", func(ctx *Context) {})
+		w := performRequestWith(e, "OPTIONS", "/items", http.Header{
+			HeaderOrigin:                     {"https://any.com"},
+			HeaderAccessControlRequestMethod: {"POST"},
+		}, nil)
+		So(w.Code, ShouldEqual, http.StatusNoContent)
+		So(w.Header().Get(HeaderAccessControlAllowOrigin), ShouldEqual, "*")
+		So(w.Header().Get(HeaderVary), ShouldEqual, "")
+	})
+}
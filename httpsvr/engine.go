@@ -1,9 +1,12 @@
 package httpsvr
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/julienschmidt/httprouter"
@@ -22,6 +25,14 @@ type Engine struct {
 	router     *httprouter.Router
 	allNoRoute []HandlerFunc
 	pool       sync.Pool
+	templates  *Templates
+	server     *HTTPServer
+	serverOnce sync.Once
+	// routes and namedRoutes describe what is registered with router
+	routes      []*RouteInfo
+	namedRoutes map[string]*RouteInfo
+	noRoute     []*fallbackRoute
+	noMethod    []*fallbackRoute
 }
 
 func Version() string {
@@ -49,8 +60,10 @@ func New() *Engine {
 	}
 	engine.router = httprouter.New()
 	engine.router.NotFound = engine.handle404
+	engine.router.MethodNotAllowed = engine.handle405
 	engine.pool.New = func() interface{} {
 		ctx := &Context{Engine: engine}
+		ctx.HtmlEngine = contextHtml{ctx}
 		return ctx
 	}
 	return engine
@@ -66,41 +79,171 @@ func (c *Engine) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	c.router.ServeHTTP(res, req)
 }
 
-// Run run the http server.
+// Run run the http server until Shutdown is called or a termination signal
+// is received.
 func (c *Engine) Run(addr string) error {
 	fmt.Printf("[%s] Listening and serving HTTP on %s \n", c.AppName, addr)
-
-	if err := http.ListenAndServe(addr, c); err != nil {
-		return err
-	}
-	return nil
+	return c.httpServer().Run(addr)
 }
 
-// Run run the https server.
+// Run run the https server, see Run.
 func (c *Engine) RunTLS(addr string, cert string, key string) error {
 	fmt.Printf("[%s] Listening and serving HTTPS on %s \n", c.AppName, addr)
+	return c.httpServer().RunAsHttps(addr, cert, key)
+}
+
+// RunTLSWith run the https server with the TLS options, see Run.
+func (c *Engine) RunTLSWith(addr string, opts TLSOptions) error {
+	fmt.Printf("[%s] Listening and serving HTTPS on %s \n", c.AppName, addr)
+	return c.httpServer().RunListeners(ListenConfig{Addr: addr, TLS: &opts})
+}
+
+// RunUnix run the http server on the unix socket file, see Run.
+func (c *Engine) RunUnix(file string, mode os.FileMode) error {
+	fmt.Printf("[%s] Listening and serving HTTP on unix:%s \n", c.AppName, file)
+	return c.httpServer().RunListeners(ListenConfig{Network: "unix", Addr: file, Mode: mode})
+}
+
+// RunListeners run the http server on every listener at once, see Run.
+func (c *Engine) RunListeners(listeners ...ListenConfig) error {
+	for _, l := range listeners {
+		l = l.normalize()
+		scheme := "HTTP"
+		if l.isTLS() {
+			scheme = "HTTPS"
+		}
+		fmt.Printf("[%s] Listening and serving %s on %s \n", c.AppName, scheme, l)
+	}
+	return c.httpServer().RunListeners(listeners...)
+}
+
+// Shutdown gracefully shuts down the servers started by Run and RunTLS,
+// waiting for in-flight requests until ctx is done.
+func (c *Engine) Shutdown(ctx context.Context) error {
+	return c.httpServer().Shutdown(ctx)
+}
+
+func (c *Engine) httpServer() *HTTPServer {
+	c.serverOnce.Do(func() {
+		c.server = newHTTPServer(c)
+	})
+	return c.server
+}
+
+// fallbackRoute is a NoRoute or NoMethod chain registered for the paths
+// under prefix.
+type fallbackRoute struct {
+	prefix   string
+	handlers []HandlerFunc
+	// own are the handlers given to NoRoute, without the middlewares
+	own []HandlerFunc
+}
+
+// addFallback registers a fallback chain, the longest prefixes come first.
+func addFallback(fallbacks []*fallbackRoute, f *fallbackRoute) []*fallbackRoute {
+	for i, other := range fallbacks {
+		if other.prefix == f.prefix {
+			fallbacks[i] = f
+			return fallbacks
+		}
+	}
+	fallbacks = append(fallbacks, f)
+	sort.SliceStable(fallbacks, func(i, j int) bool {
+		return len(fallbacks[i].prefix) > len(fallbacks[j].prefix)
+	})
+	return fallbacks
+}
 
-	if err := http.ListenAndServeTLS(addr, cert, key, c); err != nil {
-		return err
+// findFallback returns the fallback of the longest prefix of path, or nil.
+func findFallback(fallbacks []*fallbackRoute, path string) *fallbackRoute {
+	for _, f := range fallbacks {
+		prefix := strings.TrimSuffix(f.prefix, "/")
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return f
+		}
 	}
 	return nil
 }
 
+// handle404 runs the NoRoute handlers of the path, or the engine
+// middlewares, then writes the 404 page unless they answered.
 func (c *Engine) handle404(w http.ResponseWriter, req *http.Request) {
-	ctx := c.createContext(w, req, nil, c.allNoRoute, nil)
+	handlers := c.allNoRoute
+	if f := findFallback(c.noRoute, req.URL.Path); f != nil {
+		handlers = f.handlers
+	}
+	ctx := c.createContext(w, req, nil, handlers, nil)
+	c.runNotFound(ctx)
+	c.reuseContext(ctx)
+}
+
+func (c *Engine) runNotFound(ctx *Context) {
 	ctx.Writer.WriteHeader(404)
 	ctx.Next()
-	if !ctx.Writer.Written() {
-		if ctx.Writer.Status() == 404 {
-			ctx.Writer.Header().Set("Content-Type", "text/html")
-			ctx.Writer.Write([]byte(`<!DOCTYPE html><html><head><meta charset="UTF-8"><title>404 PAGE NOT FOUND</title></head><body style="padding:0;text-align:center;"><div style="padding-top:1em;font-size:2.5em;">404 PAGE NOT FOUND</div><div style="font-size:1em;color:#999;">Powered by server</div></body></html>`))
-		} else {
-			ctx.Writer.WriteHeader(ctx.Writer.Status())
-		}
+	if !ctx.Writer.Written() && ctx.Writer.Status() == 404 {
+		writeNotFound(ctx)
 	}
+	ctx.Writer.WriteHeaderNow()
+}
+
+// notFound answers 404 inside a route, e.g. for a missing static file, with
+// the NoRoute handlers of the path but not their middlewares which already
+// ran.
+func (c *Engine) notFound(ctx *Context) {
+	f := findFallback(c.noRoute, ctx.Req.URL.Path)
+	if f == nil {
+		writeNotFound(ctx)
+		return
+	}
+	sub := c.createContext(ctx.Writer, ctx.Req, ctx.Params, f.own, nil)
+	sub.Keys = ctx.Keys
+	sub.Session = ctx.Session
+	c.runNotFound(sub)
+	c.reuseContext(sub)
+}
+
+const notFoundPage = `<!DOCTYPE html><html><head><meta charset="UTF-8"><title>404 PAGE NOT FOUND</title></head><body style="padding:0;text-align:center;"><div style="padding-top:1em;font-size:2.5em;">404 PAGE NOT FOUND</div><div style="font-size:1em;color:#999;">Powered by server</div></body></html>`
+
+// writeNotFound writes the 404 page.
+func writeNotFound(ctx *Context) {
+	ctx.Writer.Header().Set("Content-Type", "text/html")
+	ctx.Writer.WriteHeader(404)
+	ctx.Writer.Write([]byte(notFoundPage))
+}
+
+// handle405 runs the NoMethod handlers of a path registered with other
+// methods only, or the engine middlewares so that they can answer e.g. CORS
+// preflight requests, then writes a 405 listing the allowed methods unless
+// they answered.
+func (c *Engine) handle405(w http.ResponseWriter, req *http.Request) {
+	handlers := c.allNoRoute
+	if f := findFallback(c.noMethod, req.URL.Path); f != nil {
+		handlers = f.handlers
+	}
+	ctx := c.createContext(w, req, nil, handlers, nil)
+	ctx.Writer.Header().Set("Allow", strings.Join(c.allowedMethods(req.URL.Path), ", "))
+	ctx.Writer.WriteHeader(http.StatusMethodNotAllowed)
+	ctx.Next()
+	if !ctx.Writer.Written() && ctx.Writer.Status() == http.StatusMethodNotAllowed {
+		ctx.Text(http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+	ctx.Writer.WriteHeaderNow()
 	c.reuseContext(ctx)
 }
 
+var routeMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// allowedMethods returns the methods registered for path.
+func (c *Engine) allowedMethods(path string) []string {
+	var methods []string
+	for _, method := range routeMethods {
+		if handle, _, _ := c.router.Lookup(method, path); handle != nil {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
 const (
 	DEV  string = "development"
 	PROD string = "production"
@@ -3,6 +3,7 @@ package httpsvr
 import (
 	"net/http"
 	"testing"
+	"testing/fstest"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -61,3 +62,59 @@ func Test_Version(t *testing.T) {
 		So(Version(), ShouldNotBeEmpty)
 	})
 }
+
+func Test_NoRoute(t *testing.T) {
+	Convey("NoRoute and NoMethod handlers answer per group prefix", t, func() {
+		var auth []string
+		m := New()
+		m.NoRoute(func(ctx *Context) { ctx.Html("<p>lost</p>", http.StatusNotFound) })
+		m.Group("/api", func(api *RouterGroup) {
+			api.Use(func(ctx *Context) { auth = append(auth, ctx.Req.URL.Path) })
+			api.GET("/users", func(ctx *Context) { ctx.Text("users") })
+			api.NoRoute(func(ctx *Context) { ctx.Json(JSON{"error": "not found"}, http.StatusNotFound) })
+			api.NoMethod(func(ctx *Context) { ctx.Json(JSON{"error": "method not allowed"}, ctx.Writer.Status()) })
+			api.StaticIOFS("/docs", fstest.MapFS{"a.txt": {Data: []byte("a")}})
+		})
+		m.GET("/page", func(ctx *Context) {})
+
+		w := performRequest(m, "GET", "/api/missing")
+		So(w.Code, ShouldEqual, http.StatusNotFound)
+		So(w.Body.String(), ShouldEqual, `{"error":"not found"}`)
+		So(auth, ShouldResemble, []string{"/api/missing"})
+
+		w = performRequest(m, "DELETE", "/api/users")
+		So(w.Code, ShouldEqual, http.StatusMethodNotAllowed)
+		So(w.Header().Get("Allow"), ShouldEqual, "GET")
+		So(w.Body.String(), ShouldEqual, `{"error":"method not allowed"}`)
+
+		w = performRequest(m, "GET", "/api/docs/b.txt")
+		So(w.Code, ShouldEqual, http.StatusNotFound)
+		So(w.Body.String(), ShouldEqual, `{"error":"not found"}`)
+
+		w = performRequest(m, "GET", "/apis")
+		So(w.Body.String(), ShouldEqual, "<p>lost</p>")
+
+		// no NoMethod outside /api, the built-in 405
+		w = performRequest(m, "POST", "/page")
+		So(w.Code, ShouldEqual, http.StatusMethodNotAllowed)
+		So(w.Body.String(), ShouldEqual, http.StatusText(http.StatusMethodNotAllowed))
+	})
+
+	Convey("NoRoute handlers writing nothing get the built-in page", t, func() {
+		m := New()
+		m.NoRoute(func(ctx *Context) { ctx.SetHeader("X-Seen", "1") })
+		w := performRequest(m, "GET", "/missing")
+		So(w.Code, ShouldEqual, http.StatusNotFound)
+		So(w.Header().Get("X-Seen"), ShouldEqual, "1")
+		So(w.Body.String(), ShouldEqual, notFoundPage)
+	})
+
+	Convey("A static handler can be the NoRoute handler", t, func() {
+		m := New()
+		m.GET("/api/ping", func(ctx *Context) { ctx.Text("pong") })
+		m.NoRoute(StaticHandler(http.FS(fstest.MapFS{"index.html": {Data: []byte("home")}}), StaticOptions{}))
+		So(performRequest(m, "GET", "/").Body.String(), ShouldEqual, "home")
+		So(performRequest(m, "GET", "/").Code, ShouldEqual, 200)
+		So(performRequest(m, "GET", "/nope.js").Body.String(), ShouldEqual, notFoundPage)
+	})
+}
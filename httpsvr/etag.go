@@ -0,0 +1,257 @@
+package httpsvr
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderETag              = "ETag"
+	HeaderLastModified      = "Last-Modified"
+	HeaderIfMatch           = "If-Match"
+	HeaderIfNoneMatch       = "If-None-Match"
+	HeaderIfModifiedSince   = "If-Modified-Since"
+	HeaderIfUnmodifiedSince = "If-Unmodified-Since"
+)
+
+// ETagOptions configures ETag.
+type ETagOptions struct {
+	// Weak makes the computed ETags weak, for responses which are equal in
+	// meaning but not byte for byte, e.g. compressed.
+	Weak bool
+}
+
+// ComputeETag returns the ETag of data, quoted and prefixed by W/ if weak.
+func ComputeETag(data []byte, weak bool) string {
+	sum := sha1.Sum(data)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	if weak {
+		etag = "W/" + etag
+	}
+	return etag
+}
+
+// ETag returns a middleware buffering the 200 responses to GET and HEAD
+// requests to set their ETag, unless the handler did, and answering 304 when
+// If-None-Match or If-Modified-Since tell the client has them already.
+// Flushed responses are not buffered and get no ETag.
+func ETag(opts ...ETagOptions) HandlerFunc {
+	var o ETagOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	return func(ctx *Context) {
+		if ctx.Req.Method != "GET" && ctx.Req.Method != "HEAD" {
+			return
+		}
+		ew := &etagWriter{ResponseWriter: ctx.Writer}
+		ctx.Writer = ew
+		ctx.Next()
+		ctx.Writer = ew.ResponseWriter
+		if ew.streaming {
+			return
+		}
+
+		header := ctx.Writer.Header()
+		if ctx.Writer.Status() == http.StatusOK {
+			if header.Get(HeaderETag) == "" && ew.wrote {
+				header.Set(HeaderETag, ComputeETag(ew.buf.Bytes(), o.Weak))
+			}
+			if notModified(ctx.Req, header) {
+				writeNotModified(ctx)
+				return
+			}
+		}
+		if ew.wrote {
+			ctx.Writer.Write(ew.buf.Bytes())
+		}
+	}
+}
+
+// etagWriter buffers the body until the handlers return.
+type etagWriter struct {
+	ResponseWriter
+	buf       bytes.Buffer
+	wrote     bool
+	streaming bool
+}
+
+func (w *etagWriter) Write(data []byte) (int, error) {
+	if w.streaming {
+		return w.ResponseWriter.Write(data)
+	}
+	w.wrote = true
+	return w.buf.Write(data)
+}
+
+func (w *etagWriter) Written() bool {
+	return w.wrote || w.ResponseWriter.Written()
+}
+
+func (w *etagWriter) Size() int {
+	if !w.streaming && w.wrote {
+		return w.buf.Len()
+	}
+	return w.ResponseWriter.Size()
+}
+
+// WriteHeaderNow stops the buffering, the headers are sent.
+func (w *etagWriter) WriteHeaderNow() {
+	w.stream()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *etagWriter) Flush() {
+	w.stream()
+	w.ResponseWriter.Flush()
+}
+
+func (w *etagWriter) stream() {
+	if w.streaming {
+		return
+	}
+	w.streaming = true
+	w.ResponseWriter.WriteHeaderNow()
+	if w.wrote {
+		w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+// SetETag sets the ETag of the response, etag is quoted if it is not.
+func (c *Context) SetETag(etag string, weak bool) {
+	if !strings.HasPrefix(etag, `"`) {
+		etag = `"` + etag + `"`
+	}
+	if weak {
+		etag = "W/" + etag
+	}
+	c.Writer.Header().Set(HeaderETag, etag)
+}
+
+// SetLastModified sets the Last-Modified of the response.
+func (c *Context) SetLastModified(modified time.Time) {
+	c.Writer.Header().Set(HeaderLastModified, modified.UTC().Format(http.TimeFormat))
+}
+
+// NotModified sets the ETag and Last-Modified of the response, either may be
+// empty, then answers 304 and aborts when the client has it already. The
+// handler returns when it is true:
+//
+//	if ctx.NotModified(item.Version, item.Updated) {
+//		return
+//	}
+func (c *Context) NotModified(etag string, modified time.Time) bool {
+	c.setValidators(etag, modified)
+	if c.Req.Method != "GET" && c.Req.Method != "HEAD" {
+		return false
+	}
+	if !notModified(c.Req, c.Writer.Header()) {
+		return false
+	}
+	writeNotModified(c)
+	c.Abort()
+	return true
+}
+
+// Precondition checks If-Match and If-Unmodified-Since against the current
+// ETag and Last-Modified of the resource, either may be empty. When they
+// fail it answers 412 and aborts, the handler returns when it is false:
+//
+//	if !ctx.Precondition(item.Version, item.Updated) {
+//		return
+//	}
+func (c *Context) Precondition(etag string, modified time.Time) bool {
+	header := make(http.Header)
+	if etag != "" && !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, "W/") {
+		etag = `"` + etag + `"`
+	}
+	header.Set(HeaderETag, etag)
+	if !modified.IsZero() {
+		header.Set(HeaderLastModified, modified.UTC().Format(http.TimeFormat))
+	}
+	if !preconditionFailed(c.Req, header) {
+		return true
+	}
+	c.Text(http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+	c.Abort()
+	return false
+}
+
+func (c *Context) setValidators(etag string, modified time.Time) {
+	if etag != "" {
+		if strings.HasPrefix(etag, "W/") {
+			c.Writer.Header().Set(HeaderETag, etag)
+		} else {
+			c.SetETag(etag, false)
+		}
+	}
+	if !modified.IsZero() {
+		c.SetLastModified(modified)
+	}
+}
+
+func writeNotModified(ctx *Context) {
+	header := ctx.Writer.Header()
+	header.Del(HeaderContentType)
+	header.Del(HeaderContentLength)
+	ctx.Writer.WriteHeader(http.StatusNotModified)
+	ctx.Writer.WriteHeaderNow()
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since without it,
+// against the validators in header.
+func notModified(req *http.Request, header http.Header) bool {
+	if inm := req.Header.Get(HeaderIfNoneMatch); inm != "" {
+		return matchETag(inm, header.Get(HeaderETag), false)
+	}
+	ims, err := http.ParseTime(req.Header.Get(HeaderIfModifiedSince))
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(header.Get(HeaderLastModified))
+	return err == nil && !modified.After(ims)
+}
+
+// preconditionFailed evaluates If-Match, or If-Unmodified-Since without it,
+// against the validators in header.
+func preconditionFailed(req *http.Request, header http.Header) bool {
+	if im := req.Header.Get(HeaderIfMatch); im != "" {
+		return !matchETag(im, header.Get(HeaderETag), true)
+	}
+	ius, err := http.ParseTime(req.Header.Get(HeaderIfUnmodifiedSince))
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(header.Get(HeaderLastModified))
+	return err == nil && modified.After(ius)
+}
+
+// matchETag reports whether the list of an If-Match or If-None-Match header
+// holds etag, strong comparison ignores weak ETags.
+func matchETag(list, etag string, strong bool) bool {
+	if etag == "" {
+		return false
+	}
+	if strong && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strong && strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+		if strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
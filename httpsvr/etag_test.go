@@ -0,0 +1,80 @@
+package httpsvr
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_ETag(t *testing.T) {
+	Convey("ETag tags responses and answers 304 to fresh clients", t, func() {
+		body := "the same body"
+		e := New()
+		e.Use(ETag())
+		e.GET("/poll", func(ctx *Context) { ctx.Text(body) })
+		e.GET("/error", func(ctx *Context) { ctx.Text("oops", 500) })
+
+		w := performRequest(e, "GET", "/poll")
+		etag := w.Header().Get(HeaderETag)
+		So(etag, ShouldEqual, ComputeETag([]byte(body), false))
+		So(w.Body.String(), ShouldEqual, body)
+
+		w = performRequestWith(e, "GET", "/poll", http.Header{HeaderIfNoneMatch: {`"other", ` + etag}}, nil)
+		So(w.Code, ShouldEqual, http.StatusNotModified)
+		So(w.Body.Len(), ShouldEqual, 0)
+		So(w.Header().Get(HeaderETag), ShouldEqual, etag)
+		So(performRequestWith(e, "GET", "/poll", http.Header{HeaderIfNoneMatch: {"W/" + etag}}, nil).Code, ShouldEqual, http.StatusNotModified)
+
+		body = "a new body"
+		w = performRequestWith(e, "GET", "/poll", http.Header{HeaderIfNoneMatch: {etag}}, nil)
+		So(w.Code, ShouldEqual, 200)
+		So(w.Body.String(), ShouldEqual, body)
+
+		w = performRequestWith(e, "GET", "/error", http.Header{HeaderIfNoneMatch: {"*"}}, nil)
+		So(w.Code, ShouldEqual, 500)
+		So(w.Header().Get(HeaderETag), ShouldEqual, "")
+	})
+
+	Convey("Context helpers handle conditional reads and writes", t, func() {
+		updated := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		version := "v1"
+		e := New()
+		e.GET("/item", func(ctx *Context) {
+			if ctx.NotModified(version, updated) {
+				return
+			}
+			ctx.Text("item " + version)
+		})
+		e.PUT("/item", func(ctx *Context) {
+			if !ctx.Precondition(version, updated) {
+				return
+			}
+			version = "v2"
+			ctx.Text("updated")
+		})
+
+		w := performRequest(e, "GET", "/item")
+		So(w.Header().Get(HeaderETag), ShouldEqual, `"v1"`)
+		So(w.Header().Get(HeaderLastModified), ShouldEqual, "Thu, 02 Jan 2020 03:04:05 GMT")
+
+		So(performRequestWith(e, "GET", "/item", http.Header{HeaderIfNoneMatch: {`"v1"`}}, nil).Code, ShouldEqual, http.StatusNotModified)
+		So(performRequestWith(e, "GET", "/item", http.Header{HeaderIfModifiedSince: {"Thu, 02 Jan 2020 03:04:05 GMT"}}, nil).Code, ShouldEqual, http.StatusNotModified)
+		So(performRequestWith(e, "GET", "/item", http.Header{HeaderIfModifiedSince: {"Wed, 01 Jan 2020 00:00:00 GMT"}}, nil).Code, ShouldEqual, 200)
+		// If-None-Match wins over If-Modified-Since
+		So(performRequestWith(e, "GET", "/item", http.Header{
+			HeaderIfNoneMatch:     {`"v0"`},
+			HeaderIfModifiedSince: {"Thu, 02 Jan 2020 03:04:05 GMT"},
+		}, nil).Code, ShouldEqual, 200)
+
+		So(performRequestWith(e, "PUT", "/item", http.Header{HeaderIfMatch: {`"v0"`}}, nil).Code, ShouldEqual, http.StatusPreconditionFailed)
+		So(performRequestWith(e, "PUT", "/item", http.Header{HeaderIfMatch: {`W/"v1"`}}, nil).Code, ShouldEqual, http.StatusPreconditionFailed)
+		So(performRequestWith(e, "PUT", "/item", http.Header{HeaderIfUnmodifiedSince: {"Wed, 01 Jan 2020 00:00:00 GMT"}}, nil).Code, ShouldEqual, http.StatusPreconditionFailed)
+		So(version, ShouldEqual, "v1")
+		w = performRequestWith(e, "PUT", "/item", http.Header{HeaderIfMatch: {`"v1"`}}, nil)
+		So(w.Code, ShouldEqual, 200)
+		So(version, ShouldEqual, "v2")
+		So(performRequestWith(e, "PUT", "/item", http.Header{HeaderIfMatch: {`"v1"`}}, nil).Code, ShouldEqual, http.StatusPreconditionFailed)
+	})
+}
@@ -3,10 +3,15 @@ package httpsvr
 import (
 	"bufio"
 	"compress/gzip"
+	"compress/zlib"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 const (
@@ -20,46 +25,295 @@ const (
 	BestSpeed          = gzip.BestSpeed
 	DefaultCompression = gzip.DefaultCompression
 	NoCompression      = gzip.NoCompression
+
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
 )
 
-// Gzip returns a Handler that adds gzip compression to all requests
+// DefaultCompressMinLength is the default CompressOptions.MinLength.
+const DefaultCompressMinLength = 1024
+
+// DefaultCompressExcludeTypes are media types already compressed.
+var DefaultCompressExcludeTypes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp", "image/avif",
+	"audio/", "video/", "font/woff", "font/woff2",
+	"application/zip", "application/gzip", "application/x-gzip",
+	"application/pdf", "application/octet-stream",
+}
+
+// CompressOptions configures Compress.
+type CompressOptions struct {
+	// Level of compression, default DefaultCompression. The zero value,
+	// NoCompression, stands for the default, Gzip takes any level.
+	Level int
+	// Encodings offered, EncodingGzip and EncodingDeflate, in order of
+	// preference for ties of the Accept-Encoding q-values. Default both.
+	Encodings []string
+	// MinLength is the smallest body compressed, default
+	// DefaultCompressMinLength. Shorter bodies are sent as is.
+	MinLength int
+	// Types are the media types compressed, "text/" matches any text type.
+	// Default any type but the ExcludeTypes.
+	Types []string
+	// ExcludeTypes are never compressed, default DefaultCompressExcludeTypes.
+	ExcludeTypes []string
+}
+
+// Gzip returns a Handler that adds gzip compression to the responses, see
+// Compress.
 func Gzip(compressionLevel int) HandlerFunc {
+	return compress(CompressOptions{Encodings: []string{EncodingGzip}}, compressionLevel)
+}
+
+// Compress returns a middleware compressing the responses with the encoding
+// the client prefers in Accept-Encoding. Bodies shorter than MinLength,
+// excluded types, responses already encoded and HEAD, 204 and 304 responses
+// are sent as is. The first MinLength bytes are buffered to decide, Flush
+// decides early. An invalid level panics.
+func Compress(opts CompressOptions) HandlerFunc {
+	if opts.Level == NoCompression {
+		opts.Level = DefaultCompression
+	}
+	return compress(opts, opts.Level)
+}
+
+func compress(opts CompressOptions, level int) HandlerFunc {
+	if len(opts.Encodings) == 0 {
+		opts.Encodings = []string{EncodingGzip, EncodingDeflate}
+	}
+	if opts.MinLength <= 0 {
+		opts.MinLength = DefaultCompressMinLength
+	}
+	if opts.ExcludeTypes == nil {
+		opts.ExcludeTypes = DefaultCompressExcludeTypes
+	}
+	pools := make(map[string]*sync.Pool, len(opts.Encodings))
+	for _, encoding := range opts.Encodings {
+		pools[encoding] = newEncoderPool(encoding, level)
+	}
+
 	return func(ctx *Context) {
-		if !strings.Contains(ctx.Req.Header.Get(HeaderAcceptEncoding), "gzip") {
+		header := ctx.Writer.Header()
+		if !containsFold(header[HeaderVary], HeaderAcceptEncoding) {
+			header.Add(HeaderVary, HeaderAcceptEncoding)
+		}
+		if ctx.Req.Method == "HEAD" {
 			return
 		}
-
-		ctx.Writer.Header().Set(HeaderContentEncoding, "gzip")
-		ctx.Writer.Header().Set(HeaderVary, HeaderAcceptEncoding)
-		gz := gzip.NewWriter(ctx.Writer)
-
-		gz, err := gzip.NewWriterLevel(ctx.Writer, compressionLevel)
-		if err != nil {
-			ctx.Next()
+		encoding := negotiateEncoding(ctx.Req.Header.Get(HeaderAcceptEncoding), opts.Encodings)
+		if encoding == "" {
 			return
 		}
-		defer gz.Close()
 
-		gzw := gzipResponseWriter{gz, ctx.Writer}
-		ctx.Writer = gzw
+		cw := &compressWriter{ResponseWriter: ctx.Writer, opts: &opts, encoding: encoding, pool: pools[encoding]}
+		ctx.Writer = cw
+		defer func() {
+			cw.close()
+			ctx.Writer = cw.ResponseWriter
+		}()
 		ctx.Next()
 	}
 }
 
-type gzipResponseWriter struct {
-	w *gzip.Writer
+// encoder is what gzip.Writer and zlib.Writer have in common.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+func newEncoderPool(encoding string, level int) *sync.Pool {
+	var newEncoder func() (encoder, error)
+	switch encoding {
+	case EncodingGzip:
+		newEncoder = func() (encoder, error) { return gzip.NewWriterLevel(ioutil.Discard, level) }
+	case EncodingDeflate:
+		newEncoder = func() (encoder, error) { return zlib.NewWriterLevel(ioutil.Discard, level) }
+	default:
+		panic("httpsvr: unknown content encoding " + encoding)
+	}
+	if _, err := newEncoder(); err != nil {
+		panic("httpsvr: " + err.Error())
+	}
+	return &sync.Pool{New: func() interface{} {
+		enc, _ := newEncoder()
+		return enc
+	}}
+}
+
+// negotiateEncoding returns the offer with the highest q-value in the
+// Accept-Encoding header, ties go to the earlier offer, or "" for none.
+func negotiateEncoding(header string, offers []string) string {
+	if header == "" {
+		return ""
+	}
+	qs := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		if coding == "" {
+			continue
+		}
+		if coding == "x-gzip" {
+			coding = EncodingGzip
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.ToLower(kv[0]) == "q" {
+				if v, err := strconv.ParseFloat(kv[1], 64); err == nil && v >= 0 && v <= 1 {
+					q = v
+				}
+			}
+		}
+		qs[coding] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, ok := qs[offer]
+		if !ok {
+			q = qs["*"]
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the start of the body until it knows whether to
+// compress it.
+type compressWriter struct {
 	ResponseWriter
+	opts     *CompressOptions
+	encoding string
+	pool     *sync.Pool
+	buf      []byte
+	decided  bool
+	enc      encoder
+}
+
+func (c *compressWriter) Write(p []byte) (int, error) {
+	if c.decided {
+		return c.write(p)
+	}
+	c.buf = append(c.buf, p...)
+	if len(c.buf) < c.opts.MinLength && !c.lengthKnown() {
+		return len(p), nil
+	}
+	if err := c.decide(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *compressWriter) write(p []byte) (int, error) {
+	if c.enc != nil {
+		return c.enc.Write(p)
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+// lengthKnown reports whether the Content-Length is set, then there is no
+// need to buffer.
+func (c *compressWriter) lengthKnown() bool {
+	return c.Header().Get(HeaderContentLength) != ""
+}
+
+func (c *compressWriter) Written() bool {
+	return len(c.buf) > 0 || c.ResponseWriter.Written()
+}
+
+func (c *compressWriter) WriteHeaderNow() {
+	c.decide()
+	c.ResponseWriter.WriteHeaderNow()
+}
+
+func (c *compressWriter) Flush() {
+	c.decide()
+	if c.enc != nil {
+		c.enc.Flush()
+	}
+	c.ResponseWriter.Flush()
+}
+
+// decide picks compressed or not, then writes what is buffered.
+func (c *compressWriter) decide() error {
+	if c.decided {
+		return nil
+	}
+	c.decided = true
+	header := c.Header()
+	if len(c.buf) > 0 && header.Get(HeaderContentType) == "" {
+		header.Set(HeaderContentType, http.DetectContentType(c.buf))
+	}
+	if c.shouldCompress() {
+		header.Set(HeaderContentEncoding, c.encoding)
+		header.Del(HeaderContentLength)
+		// the compressed body is not byte for byte the one of a strong ETag
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		c.enc = c.pool.Get().(encoder)
+		c.enc.Reset(c.ResponseWriter)
+	}
+	if len(c.buf) == 0 {
+		return nil
+	}
+	buf := c.buf
+	c.buf = nil
+	_, err := c.write(buf)
+	return err
+}
+
+func (c *compressWriter) shouldCompress() bool {
+	status := c.Status()
+	if status < 200 || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	header := c.Header()
+	if header.Get(HeaderContentEncoding) != "" {
+		return false
+	}
+	if cl := header.Get(HeaderContentLength); cl != "" {
+		if n, err := strconv.Atoi(cl); err != nil || n < c.opts.MinLength {
+			return false
+		}
+	} else if len(c.buf) < c.opts.MinLength {
+		return false
+	}
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(header.Get(HeaderContentType), ";")[0]))
+	if len(c.opts.Types) > 0 && !matchMediaType(c.opts.Types, mediaType) {
+		return false
+	}
+	return !matchMediaType(c.opts.ExcludeTypes, mediaType)
+}
+
+// matchMediaType reports whether mediaType is one of types, or starts with
+// one of them ending in "/".
+func matchMediaType(types []string, mediaType string) bool {
+	for _, t := range types {
+		if t == mediaType || strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t) {
+			return true
+		}
+	}
+	return false
 }
 
-func (c gzipResponseWriter) Write(p []byte) (int, error) {
-	if len(c.Header().Get(HeaderContentType)) == 0 {
-		c.Header().Set(HeaderContentType, http.DetectContentType(p))
+// close writes what is still buffered and returns the encoder to its pool.
+func (c *compressWriter) close() {
+	c.decide()
+	if c.enc != nil {
+		c.enc.Close()
+		c.enc.Reset(ioutil.Discard)
+		c.pool.Put(c.enc)
+		c.enc = nil
 	}
-	return c.w.Write(p)
 }
 
-func (grw gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
-	hijacker, ok := grw.ResponseWriter.(http.Hijacker)
+func (c *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := c.ResponseWriter.(http.Hijacker)
 	if !ok {
 		return nil, nil, fmt.Errorf("the ResponseWriter doesn't support the Hijacker interface")
 	}
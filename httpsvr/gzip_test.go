@@ -0,0 +1,108 @@
+package httpsvr
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_NegotiateEncoding(t *testing.T) {
+	Convey("Accept-Encoding q-values pick the encoding", t, func() {
+		offers := []string{EncodingGzip, EncodingDeflate}
+		So(negotiateEncoding("", offers), ShouldEqual, "")
+		So(negotiateEncoding("gzip, deflate", offers), ShouldEqual, EncodingGzip)
+		So(negotiateEncoding("gzip;q=0.5, deflate", offers), ShouldEqual, EncodingDeflate)
+		So(negotiateEncoding("x-gzip", offers), ShouldEqual, EncodingGzip)
+		So(negotiateEncoding("*", offers), ShouldEqual, EncodingGzip)
+		So(negotiateEncoding("*;q=0.1, gzip;q=0", offers), ShouldEqual, EncodingDeflate)
+		So(negotiateEncoding("br, identity", offers), ShouldEqual, "")
+	})
+}
+
+func Test_Compress(t *testing.T) {
+	long := strings.Repeat("compress me please ", 100)
+
+	Convey("Compress negotiates and skips what is not worth it", t, func() {
+		e := New()
+		e.Use(Compress(CompressOptions{}))
+		e.GET("/long", func(ctx *Context) {
+			ctx.SetHeader("ETag", `"v1"`)
+			ctx.Text(long)
+		})
+		e.GET("/short", func(ctx *Context) { ctx.Text("short") })
+		e.GET("/image", func(ctx *Context) { ctx.Image([]byte(long)) })
+		e.GET("/encoded", func(ctx *Context) {
+			ctx.SetHeader(HeaderContentEncoding, "br")
+			ctx.Text(long)
+		})
+		e.GET("/empty", func(ctx *Context) { ctx.Writer.WriteHeader(http.StatusNoContent) })
+
+		w := performRequestWith(e, "GET", "/long", http.Header{HeaderAcceptEncoding: {"gzip, deflate"}}, nil)
+		So(w.Header().Get(HeaderContentEncoding), ShouldEqual, EncodingGzip)
+		So(w.Header().Get(HeaderVary), ShouldEqual, HeaderAcceptEncoding)
+		So(w.Header().Get("ETag"), ShouldEqual, `W/"v1"`)
+		So(w.Header().Get(HeaderContentType), ShouldStartWith, "text/plain")
+		gz, err := gzip.NewReader(w.Body)
+		So(err, ShouldBeNil)
+		body, _ := ioutil.ReadAll(gz)
+		So(string(body), ShouldEqual, long)
+
+		w = performRequestWith(e, "GET", "/long", http.Header{HeaderAcceptEncoding: {"gzip;q=0.2, deflate"}}, nil)
+		So(w.Header().Get(HeaderContentEncoding), ShouldEqual, EncodingDeflate)
+		zr, err := zlib.NewReader(w.Body)
+		So(err, ShouldBeNil)
+		body, _ = ioutil.ReadAll(zr)
+		So(string(body), ShouldEqual, long)
+
+		w = performRequest(e, "GET", "/long")
+		So(w.Header().Get(HeaderContentEncoding), ShouldEqual, "")
+		So(w.Header().Get(HeaderVary), ShouldEqual, HeaderAcceptEncoding)
+		So(w.Body.String(), ShouldEqual, long)
+
+		for _, path := range []string{"/short", "/image", "/empty"} {
+			So(performRequestWith(e, "GET", path, http.Header{HeaderAcceptEncoding: {"gzip"}}, nil).Header().Get(HeaderContentEncoding), ShouldEqual, "")
+		}
+		So(performRequestWith(e, "GET", "/short", http.Header{HeaderAcceptEncoding: {"gzip"}}, nil).Body.String(), ShouldEqual, "short")
+		So(performRequestWith(e, "GET", "/empty", http.Header{HeaderAcceptEncoding: {"gzip"}}, nil).Code, ShouldEqual, http.StatusNoContent)
+		So(performRequestWith(e, "GET", "/encoded", http.Header{HeaderAcceptEncoding: {"gzip"}}, nil).Header().Get(HeaderContentEncoding), ShouldEqual, "br")
+		So(performRequestWith(e, "HEAD", "/long", http.Header{HeaderAcceptEncoding: {"gzip"}}, nil).Header().Get(HeaderContentEncoding), ShouldEqual, "")
+	})
+
+	Convey("Compress reuses its encoders", t, func() {
+		e := New()
+		e.Use(Gzip(BestSpeed))
+		e.GET("/long", func(ctx *Context) { ctx.Text(long) })
+		for i := 0; i < 3; i++ {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/long", nil)
+			req.Header.Set(HeaderAcceptEncoding, "deflate, gzip")
+			e.ServeHTTP(w, req)
+			So(w.Header().Get(HeaderContentEncoding), ShouldEqual, EncodingGzip)
+			gz, _ := gzip.NewReader(w.Body)
+			body, _ := ioutil.ReadAll(gz)
+			So(string(body), ShouldEqual, long)
+		}
+		So(func() { Gzip(42) }, ShouldPanic)
+	})
+
+	Convey("NoCompression stores the body", t, func() {
+		e := New()
+		e.Use(Gzip(NoCompression))
+		e.GET("/long", func(ctx *Context) { ctx.Text(long) })
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/long", nil)
+		req.Header.Set(HeaderAcceptEncoding, "gzip")
+		e.ServeHTTP(w, req)
+		So(w.Header().Get(HeaderContentEncoding), ShouldEqual, EncodingGzip)
+		So(w.Body.Len(), ShouldBeGreaterThan, len(long))
+		gz, _ := gzip.NewReader(w.Body)
+		body, _ := ioutil.ReadAll(gz)
+		So(string(body), ShouldEqual, long)
+	})
+}
@@ -0,0 +1,199 @@
+package httpsvr
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hydah/golib/logger"
+)
+
+const (
+	// envListenFDs is the number of listeners passed by a restarting parent
+	// process, starting at file descriptor 3.
+	envListenFDs = "HTTPSVR_LISTEN_FDS"
+	// envParentPID is the pid of the restarting parent process, it is sent
+	// SIGTERM once every inherited listener is served.
+	envParentPID = "HTTPSVR_PARENT_PID"
+	// listenFDsStart is the first passed file descriptor, after stdin,
+	// stdout and stderr.
+	listenFDsStart = 3
+)
+
+// inherited holds the listeners passed by a parent process or by systemd
+// socket activation (LISTEN_PID and LISTEN_FDS), they are claimed by
+// address when the server starts listening.
+var inherited struct {
+	once      sync.Once
+	lock      sync.Mutex
+	listeners []net.Listener
+	total     int
+	claimed   int
+	parentPID int
+}
+
+func loadInherited() {
+	if pid, _ := strconv.Atoi(os.Getenv("LISTEN_PID")); pid == os.Getpid() {
+		n, _ := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+		addInherited(n)
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}
+	if n, _ := strconv.Atoi(os.Getenv(envListenFDs)); n > 0 {
+		addInherited(n)
+		inherited.parentPID, _ = strconv.Atoi(os.Getenv(envParentPID))
+	}
+	os.Unsetenv(envListenFDs)
+	os.Unsetenv(envParentPID)
+}
+
+func addInherited(n int) {
+	for fd := listenFDsStart; fd < listenFDsStart+n; fd++ {
+		f := os.NewFile(uintptr(fd), "listener-"+strconv.Itoa(fd))
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			logger.Error("inherit listener fd %d: %v", fd, err)
+			continue
+		}
+		inherited.listeners = append(inherited.listeners, ln)
+		inherited.total++
+	}
+}
+
+// ListenConfig describes one address served by HTTPServer.RunListeners.
+type ListenConfig struct {
+	// Network is "tcp", "tcp4", "tcp6" or "unix", default "tcp", or "unix"
+	// when Addr starts with "unix:".
+	Network string
+	// Addr is a host:port, or the path of the unix socket.
+	Addr string
+	// Mode is the permission of the unix socket file, default depends on
+	// the umask.
+	Mode os.FileMode
+	// ReusePort sets SO_REUSEPORT so that several processes can listen on
+	// the same TCP port, on linux and the BSDs only, the listen fails
+	// elsewhere.
+	ReusePort bool
+	// TLS or TLSConfig serve HTTPS on this listener, TLS takes precedence.
+	TLS       *TLSOptions
+	TLSConfig *tls.Config
+}
+
+func (l ListenConfig) normalize() ListenConfig {
+	if l.Network == "" {
+		l.Network = "tcp"
+		if strings.HasPrefix(l.Addr, "unix:") {
+			l.Network = "unix"
+			l.Addr = strings.TrimPrefix(l.Addr, "unix:")
+		}
+	}
+	return l
+}
+
+func (l ListenConfig) isTLS() bool {
+	return l.TLS != nil || l.TLSConfig != nil
+}
+
+func (l ListenConfig) String() string {
+	if l.Network == "unix" {
+		return "unix:" + l.Addr
+	}
+	return l.Addr
+}
+
+// listen returns the inherited listener for the address of l, or a new one.
+func listen(l ListenConfig) (net.Listener, error) {
+	inherited.once.Do(loadInherited)
+	inherited.lock.Lock()
+	for i, ln := range inherited.listeners {
+		if sameAddr(ln.Addr(), l.Network, l.Addr) {
+			inherited.listeners = append(inherited.listeners[:i], inherited.listeners[i+1:]...)
+			inherited.claimed++
+			inherited.lock.Unlock()
+			return ln, nil
+		}
+	}
+	inherited.lock.Unlock()
+
+	if l.Network == "unix" {
+		return listenUnix(l)
+	}
+	var lc net.ListenConfig
+	if l.ReusePort {
+		lc.Control = reusePort
+	}
+	return lc.Listen(context.Background(), l.Network, l.Addr)
+}
+
+// listenUnix removes a stale socket file left by a crashed process before
+// listening, and sets the permission of the new one.
+func listenUnix(l ListenConfig) (net.Listener, error) {
+	if fi, err := os.Lstat(l.Addr); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", l.Addr); err == nil {
+			conn.Close()
+			return nil, errors.New("listen unix " + l.Addr + ": address already in use")
+		}
+		os.Remove(l.Addr)
+	}
+	ln, err := net.Listen("unix", l.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if l.Mode != 0 {
+		if err := os.Chmod(l.Addr, l.Mode); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
+}
+
+func sameAddr(a net.Addr, network, addr string) bool {
+	switch a := a.(type) {
+	case *net.TCPAddr:
+		want, err := net.ResolveTCPAddr(network, addr)
+		if err != nil || want.Port == 0 || want.Port != a.Port {
+			return false
+		}
+		if want.IP == nil || want.IP.IsUnspecified() {
+			return a.IP == nil || a.IP.IsUnspecified()
+		}
+		return want.IP.Equal(a.IP)
+	case *net.UnixAddr:
+		return network == a.Net && addr == a.Name
+	}
+	return false
+}
+
+// onceCloseListener lets both HTTPServer.Shutdown and http.Server close the
+// listener.
+type onceCloseListener struct {
+	net.Listener
+	once sync.Once
+	err  error
+}
+
+func (l *onceCloseListener) Close() error {
+	l.once.Do(func() {
+		l.err = l.Listener.Close()
+	})
+	return l.err
+}
+
+// keepUnixSocket stops ln from removing its socket file when closed, the
+// file then belongs to the process ln was passed to.
+func keepUnixSocket(ln net.Listener) {
+	if l, ok := ln.(*onceCloseListener); ok {
+		ln = l.Listener
+	}
+	if l, ok := ln.(*net.UnixListener); ok {
+		l.SetUnlinkOnClose(false)
+	}
+}
@@ -0,0 +1,104 @@
+package httpsvr
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_RunListeners(t *testing.T) {
+	Convey("One engine serves TCP, TLS and a unix socket at once", t, func() {
+		dir, err := ioutil.TempDir("", "httpsvr")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		sock := filepath.Join(dir, "app.sock")
+
+		// borrow the test certificate of httptest
+		ts := httptest.NewTLSServer(http.NotFoundHandler())
+		defer ts.Close()
+
+		s := NewHTTPServer()
+		s.engine.GET("/", func(ctx *Context) {
+			if ctx.Req.TLS != nil {
+				ctx.Text("tls")
+				return
+			}
+			ctx.Text("plain")
+		})
+		addr, tlsAddr := freeAddr(), freeAddr()
+		runErr := make(chan error, 1)
+		go func() {
+			runErr <- s.RunListeners(
+				ListenConfig{Addr: addr},
+				ListenConfig{Addr: tlsAddr, TLSConfig: &tls.Config{Certificates: ts.TLS.Certificates}},
+				ListenConfig{Addr: "unix:" + sock, Mode: 0600},
+			)
+		}()
+		waitListening(addr)
+		waitListening(tlsAddr)
+
+		So(get(http.DefaultClient, "http://"+addr+"/"), ShouldEqual, "plain")
+		So(get(ts.Client(), "https://"+tlsAddr+"/"), ShouldEqual, "tls")
+
+		fi, err := os.Stat(sock)
+		So(err, ShouldBeNil)
+		So(fi.Mode()&os.ModePerm, ShouldEqual, os.FileMode(0600))
+		unixClient := &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return net.Dial("unix", sock)
+			},
+		}}
+		So(get(unixClient, "http://unix/"), ShouldEqual, "plain")
+
+		So(s.Shutdown(context.Background()), ShouldBeNil)
+		So(<-runErr, ShouldBeNil)
+		_, err = os.Stat(sock)
+		So(os.IsNotExist(err), ShouldBeTrue)
+	})
+
+	Convey("A failing listener shuts the others down", t, func() {
+		busy, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer busy.Close()
+
+		s := NewHTTPServer()
+		err = s.RunListeners(ListenConfig{Addr: freeAddr()}, ListenConfig{Addr: busy.Addr().String()})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("ReusePort lets two servers listen on the same port", t, func() {
+		addr := freeAddr()
+		s1, s2 := NewHTTPServer(), NewHTTPServer()
+		go s1.RunListeners(ListenConfig{Addr: addr, ReusePort: true})
+		waitListening(addr)
+		runErr := make(chan error, 1)
+		go func() { runErr <- s2.RunListeners(ListenConfig{Addr: addr, ReusePort: true}) }()
+
+		select {
+		case err := <-runErr:
+			So(err, ShouldBeNil)
+		case <-time.After(100 * time.Millisecond):
+		}
+		So(s1.Shutdown(context.Background()), ShouldBeNil)
+		So(s2.Shutdown(context.Background()), ShouldBeNil)
+	})
+}
+
+func get(client *http.Client, url string) string {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err.Error()
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	return string(b)
+}
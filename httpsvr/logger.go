@@ -17,6 +17,8 @@ var (
 	reset   = "\033[0m"
 )
 
+// Logger returns a middleware writing a colored line per request at DEBUG,
+// for a log meant for files see AccessLog.
 func Logger() HandlerFunc {
 	return func(ctx *Context) {
 		start := time.Now()
@@ -0,0 +1,113 @@
+package httpsvr
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/hydah/golib/httpsvr/metrics"
+)
+
+// MetricsOptions configures Metrics.
+type MetricsOptions struct {
+	// Registry holds the metrics, default metrics.DefaultRegistry.
+	Registry *metrics.Registry
+	// Namespace prefixes the metric names, default "http".
+	Namespace string
+	// Buckets of the latency histogram in seconds, default
+	// metrics.DefBuckets.
+	Buckets []float64
+	// SizeBuckets of the response size histogram in bytes, default 64B to
+	// 16MB by factors of 4.
+	SizeBuckets []float64
+}
+
+// unmatchedRoute labels the requests which matched no route, so that
+// unknown paths do not create new series.
+const unmatchedRoute = "unmatched"
+
+// otherMethod labels the requests of non-standard methods, for the same
+// reason.
+const otherMethod = "OTHER"
+
+// Metrics returns a middleware recording, labeled by method (OTHER for the
+// non-standard ones), route pattern and status class (e.g. "2xx"):
+//
+//	http_requests_total                    counter
+//	http_request_duration_seconds          histogram
+//	http_response_size_bytes               histogram
+//	http_requests_in_flight                gauge, labeled by method and route
+//
+// Routes registered before it is used are not measured, see
+// Engine.ServeMetrics.
+func Metrics(opts MetricsOptions) HandlerFunc {
+	if opts.Registry == nil {
+		opts.Registry = metrics.DefaultRegistry
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = "http"
+	}
+	if len(opts.SizeBuckets) == 0 {
+		opts.SizeBuckets = metrics.ExponentialBuckets(64, 4, 10)
+	}
+	r, ns := opts.Registry, opts.Namespace
+	requests := r.CounterVec(ns+"_requests_total",
+		"Number of HTTP requests.", "method", "route", "status")
+	duration := r.HistogramVec(ns+"_request_duration_seconds",
+		"Latency of HTTP requests in seconds.", opts.Buckets, "method", "route", "status")
+	size := r.HistogramVec(ns+"_response_size_bytes",
+		"Size of HTTP response bodies in bytes.", opts.SizeBuckets, "method", "route", "status")
+	inFlight := r.GaugeVec(ns+"_requests_in_flight",
+		"Number of HTTP requests being handled.", "method", "route")
+
+	return func(ctx *Context) {
+		start := time.Now()
+		method, route := methodLabel(ctx.Req.Method), ctx.Route()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		gauge := inFlight.With(method, route)
+		gauge.Inc()
+		defer gauge.Dec()
+
+		ctx.Next()
+
+		status := statusClass(ctx.Writer.Status())
+		bytes := ctx.Writer.Size()
+		if bytes < 0 {
+			bytes = 0
+		}
+		requests.With(method, route, status).Inc()
+		duration.With(method, route, status).Observe(time.Since(start).Seconds())
+		size.With(method, route, status).Observe(float64(bytes))
+	}
+}
+
+func methodLabel(method string) string {
+	switch method {
+	case "GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "CONNECT", "OPTIONS", "TRACE":
+		return method
+	}
+	return otherMethod
+}
+
+func statusClass(status int) string {
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// ServeMetrics uses Metrics on the engine and serves the metrics on path in
+// the Prometheus text format. Call it before registering the routes to
+// measure.
+func (c *Engine) ServeMetrics(path string, opts ...MetricsOptions) {
+	var o MetricsOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Registry == nil {
+		o.Registry = metrics.DefaultRegistry
+	}
+	c.Use(Metrics(o))
+	handler := o.Registry.Handler()
+	c.GET(path, func(ctx *Context) {
+		handler.ServeHTTP(ctx.Writer, ctx.Req)
+	})
+}
@@ -0,0 +1,330 @@
+// Package metrics is a small registry of counters, gauges and histograms
+// exposed in the Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ContentType is the content type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets are the default histogram buckets, in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// ExponentialBuckets returns count buckets, the first is start and each
+// next one is factor times the previous.
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+	return buckets
+}
+
+// Registry holds metric families and writes them out.
+type Registry struct {
+	lock     sync.RWMutex
+	families map[string]*family
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// DefaultRegistry is used when no Registry is given.
+var DefaultRegistry = NewRegistry()
+
+type kind string
+
+const (
+	counterKind   kind = "counter"
+	gaugeKind     kind = "gauge"
+	histogramKind kind = "histogram"
+)
+
+// family is a metric and its children, one per set of label values.
+type family struct {
+	name    string
+	help    string
+	kind    kind
+	labels  []string
+	buckets []float64
+
+	lock     sync.RWMutex
+	children map[string]*child
+}
+
+type child struct {
+	values []string
+	value  uint64 // float64 bits of a counter or gauge
+	// histogram
+	counts []uint64
+	sum    uint64
+	count  uint64
+}
+
+// register returns the family of name, creating it if needed. Registering
+// a name again with another kind or other labels panics.
+func (r *Registry) register(name, help string, k kind, buckets []float64, labels []string) *family {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if f, ok := r.families[name]; ok {
+		if f.kind != k || strings.Join(f.labels, ",") != strings.Join(labels, ",") {
+			panic("metrics: " + name + " is already registered with another kind or labels")
+		}
+		return f
+	}
+	f := &family{
+		name:     name,
+		help:     help,
+		kind:     k,
+		labels:   labels,
+		buckets:  buckets,
+		children: make(map[string]*child),
+	}
+	r.families[name] = f
+	return f
+}
+
+func (f *family) with(values []string) *child {
+	if len(values) != len(f.labels) {
+		panic("metrics: " + f.name + " wants " + strconv.Itoa(len(f.labels)) + " label values")
+	}
+	key := strings.Join(values, "\xff")
+	f.lock.RLock()
+	c, ok := f.children[key]
+	f.lock.RUnlock()
+	if ok {
+		return c
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if c, ok = f.children[key]; !ok {
+		c = &child{values: append([]string(nil), values...)}
+		if f.kind == histogramKind {
+			c.counts = make([]uint64, len(f.buckets))
+		}
+		f.children[key] = c
+	}
+	return c
+}
+
+func addFloat(addr *uint64, delta float64) {
+	for {
+		old := atomic.LoadUint64(addr)
+		if atomic.CompareAndSwapUint64(addr, old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+func loadFloat(addr *uint64) float64 {
+	return math.Float64frombits(atomic.LoadUint64(addr))
+}
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct{ f *family }
+
+// Counter only goes up.
+type Counter struct{ c *child }
+
+// CounterVec returns the counter family name with the label names.
+func (r *Registry) CounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{r.register(name, help, counterKind, nil, labels)}
+}
+
+// With returns the counter of the label values.
+func (v *CounterVec) With(values ...string) Counter {
+	return Counter{v.f.with(values)}
+}
+
+// Inc adds 1.
+func (c Counter) Inc() { addFloat(&c.c.value, 1) }
+
+// Add adds delta, which must not be negative.
+func (c Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("metrics: counter can not decrease")
+	}
+	addFloat(&c.c.value, delta)
+}
+
+// Value returns the current value.
+func (c Counter) Value() float64 { return loadFloat(&c.c.value) }
+
+// GaugeVec is a gauge partitioned by labels.
+type GaugeVec struct{ f *family }
+
+// Gauge goes up and down.
+type Gauge struct{ c *child }
+
+// GaugeVec returns the gauge family name with the label names.
+func (r *Registry) GaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{r.register(name, help, gaugeKind, nil, labels)}
+}
+
+// With returns the gauge of the label values.
+func (v *GaugeVec) With(values ...string) Gauge {
+	return Gauge{v.f.with(values)}
+}
+
+// Inc adds 1.
+func (g Gauge) Inc() { addFloat(&g.c.value, 1) }
+
+// Dec subtracts 1.
+func (g Gauge) Dec() { addFloat(&g.c.value, -1) }
+
+// Add adds delta.
+func (g Gauge) Add(delta float64) { addFloat(&g.c.value, delta) }
+
+// Set sets the value.
+func (g Gauge) Set(value float64) { atomic.StoreUint64(&g.c.value, math.Float64bits(value)) }
+
+// Value returns the current value.
+func (g Gauge) Value() float64 { return loadFloat(&g.c.value) }
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct{ f *family }
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	c       *child
+	buckets []float64
+}
+
+// HistogramVec returns the histogram family name with the bucket upper
+// bounds, default DefBuckets, and the label names.
+func (r *Registry) HistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &HistogramVec{r.register(name, help, histogramKind, buckets, labels)}
+}
+
+// With returns the histogram of the label values.
+func (v *HistogramVec) With(values ...string) Histogram {
+	return Histogram{v.f.with(values), v.f.buckets}
+}
+
+// Observe adds a value.
+func (h Histogram) Observe(value float64) {
+	if i := sort.SearchFloat64s(h.buckets, value); i < len(h.buckets) {
+		atomic.AddUint64(&h.c.counts[i], 1)
+	}
+	addFloat(&h.c.sum, value)
+	atomic.AddUint64(&h.c.count, 1)
+}
+
+// Count returns the number of observations.
+func (h Histogram) Count() uint64 { return atomic.LoadUint64(&h.c.count) }
+
+// WriteText writes every metric in the text exposition format, families
+// sorted by name and children by label values.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.lock.RLock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.lock.RUnlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler serves the metrics of the registry.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		r.WriteText(w)
+	})
+}
+
+func (f *family) write(w *bufio.Writer) {
+	f.lock.RLock()
+	children := make([]*child, 0, len(f.children))
+	for _, c := range f.children {
+		children = append(children, c)
+	}
+	f.lock.RUnlock()
+	if len(children) == 0 {
+		return
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return strings.Join(children[i].values, "\xff") < strings.Join(children[j].values, "\xff")
+	})
+
+	if f.help != "" {
+		w.WriteString("# HELP " + f.name + " " + helpEscaper.Replace(f.help) + "\n")
+	}
+	w.WriteString("# TYPE " + f.name + " " + string(f.kind) + "\n")
+	for _, c := range children {
+		labels := f.labelPairs(c.values)
+		if f.kind != histogramKind {
+			writeSample(w, f.name, labels, "", loadFloat(&c.value))
+			continue
+		}
+		var cumulative uint64
+		for i, bound := range f.buckets {
+			cumulative += atomic.LoadUint64(&c.counts[i])
+			writeSample(w, f.name+"_bucket", labels, `le="`+formatFloat(bound)+`"`, float64(cumulative))
+		}
+		count := atomic.LoadUint64(&c.count)
+		writeSample(w, f.name+"_bucket", labels, `le="+Inf"`, float64(count))
+		writeSample(w, f.name+"_sum", labels, "", loadFloat(&c.sum))
+		writeSample(w, f.name+"_count", labels, "", float64(count))
+	}
+}
+
+func (f *family) labelPairs(values []string) string {
+	pairs := make([]string, len(values))
+	for i, value := range values {
+		pairs[i] = f.labels[i] + `="` + labelEscaper.Replace(value) + `"`
+	}
+	return strings.Join(pairs, ",")
+}
+
+func writeSample(w *bufio.Writer, name, labels, extra string, value float64) {
+	w.WriteString(name)
+	if labels != "" && extra != "" {
+		labels += ","
+	}
+	if labels+extra != "" {
+		w.WriteString("{" + labels + extra + "}")
+	}
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_Registry(t *testing.T) {
+	Convey("WriteText writes the text exposition format", t, func() {
+		r := NewRegistry()
+		c := r.CounterVec("jobs_total", "Jobs done.", "queue")
+		c.With("mail").Inc()
+		c.With("mail").Add(2)
+		c.With(`sms"x`).Inc()
+		g := r.GaugeVec("workers", "Busy\nworkers.")
+		g.With().Set(3)
+		g.With().Dec()
+		h := r.HistogramVec("latency_seconds", "", []float64{1, 0.1}, "op")
+		h.With("get").Observe(0.05)
+		h.With("get").Observe(0.5)
+		h.With("get").Observe(5)
+		r.CounterVec("unused_total", "Never touched.")
+
+		var buf bytes.Buffer
+		So(r.WriteText(&buf), ShouldBeNil)
+		So(buf.String(), ShouldEqual, `# HELP jobs_total Jobs done.
+# TYPE jobs_total counter
+jobs_total{queue="mail"} 3
+jobs_total{queue="sms\"x"} 1
+# TYPE latency_seconds histogram
+latency_seconds_bucket{op="get",le="0.1"} 1
+latency_seconds_bucket{op="get",le="1"} 2
+latency_seconds_bucket{op="get",le="+Inf"} 3
+latency_seconds_sum{op="get"} 5.55
+latency_seconds_count{op="get"} 3
+# HELP workers Busy\nworkers.
+# TYPE workers gauge
+workers 2
+`)
+	})
+
+	Convey("Registering a name again returns the same family", t, func() {
+		r := NewRegistry()
+		r.CounterVec("a_total", "", "x").With("1").Inc()
+		So(r.CounterVec("a_total", "", "x").With("1").Value(), ShouldEqual, 1)
+		So(func() { r.GaugeVec("a_total", "", "x") }, ShouldPanic)
+		So(func() { r.CounterVec("a_total", "", "y") }, ShouldPanic)
+		So(func() { r.CounterVec("a_total", "", "x").With() }, ShouldPanic)
+		So(func() { r.CounterVec("a_total", "", "x").With("1").Add(-1) }, ShouldPanic)
+	})
+
+	Convey("ExponentialBuckets", t, func() {
+		So(ExponentialBuckets(1, 2, 4), ShouldResemble, []float64{1, 2, 4, 8})
+	})
+}
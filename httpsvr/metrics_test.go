@@ -0,0 +1,47 @@
+package httpsvr
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hydah/golib/httpsvr/metrics"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_Metrics(t *testing.T) {
+	Convey("ServeMetrics exposes per route metrics", t, func() {
+		e := New()
+		e.ServeMetrics("/metrics", MetricsOptions{Registry: metrics.NewRegistry()})
+		e.GET("/users/:id", func(ctx *Context) { ctx.Text("user") })
+		e.POST("/users", func(ctx *Context) { ctx.Text("bad", 400) })
+
+		ts := httptest.NewServer(e)
+		defer ts.Close()
+		http.Get(ts.URL + "/users/1")
+		http.Get(ts.URL + "/users/2")
+		http.Post(ts.URL+"/users", "text/plain", nil)
+		http.Get(ts.URL + "/nowhere")
+		for _, method := range []string{"PROPFIND", "X-RANDOM"} {
+			req, _ := http.NewRequest(method, ts.URL+"/nowhere", nil)
+			http.DefaultClient.Do(req)
+		}
+
+		resp, err := http.Get(ts.URL + "/metrics")
+		So(err, ShouldBeNil)
+		So(resp.Header.Get("Content-Type"), ShouldEqual, metrics.ContentType)
+		body := get(http.DefaultClient, ts.URL+"/metrics")
+
+		So(body, ShouldContainSubstring, `# TYPE http_requests_total counter`)
+		So(body, ShouldContainSubstring, `http_requests_total{method="GET",route="/users/:id",status="2xx"} 2`)
+		So(body, ShouldContainSubstring, `http_requests_total{method="POST",route="/users",status="4xx"} 1`)
+		So(body, ShouldContainSubstring, `http_requests_total{method="GET",route="unmatched",status="4xx"} 1`)
+		So(body, ShouldContainSubstring, `http_request_duration_seconds_count{method="GET",route="/users/:id",status="2xx"} 2`)
+		So(body, ShouldContainSubstring, `http_response_size_bytes_bucket{method="GET",route="/users/:id",status="2xx",le="64"} 2`)
+		So(body, ShouldContainSubstring, `http_requests_in_flight{method="GET",route="/metrics"} 1`)
+		So(body, ShouldContainSubstring, `http_requests_total{method="OTHER",route="unmatched",status="4xx"} 2`)
+		So(strings.Contains(body, "/users/1"), ShouldBeFalse)
+		So(strings.Contains(body, "PROPFIND"), ShouldBeFalse)
+	})
+}
@@ -0,0 +1,161 @@
+package httpsvr
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hydah/golib/httpsvr/render"
+)
+
+// Media types understood by NegotiateFormat, Negotiate renders them all but
+// MIMEJSONP, which needs a callback.
+const (
+	MIMEJSON  = "application/json"
+	MIMEJSONP = "application/javascript"
+	MIMEXML   = "application/xml"
+	MIMEXML2  = "text/xml"
+	MIMEPlain = "text/plain"
+	MIMEHTML  = "text/html"
+)
+
+var negotiateRenders = map[string]render.Render{
+	MIMEJSON:  render.JSON{},
+	MIMEXML:   render.XML{},
+	MIMEXML2:  render.XML{},
+	MIMEPlain: render.TEXT{},
+	MIMEHTML:  render.HTML{},
+}
+
+// Negotiate renders data with the offer that best matches the Accept header
+// of the request, offers default to JSON and XML. Accept q-values and
+// wildcards are honored, ties go to the earlier offer. A request without
+// Accept gets the first offer; when no offer is acceptable a 406 listing the
+// offers is written instead. The offers Negotiate can not render are skipped.
+// For text/html a string is written as is, other data printed and escaped.
+func (c *Context) Negotiate(status int, data interface{}, offers ...string) {
+	if len(offers) == 0 {
+		offers = []string{MIMEJSON, MIMEXML}
+	}
+	c.Writer.Header().Add(HeaderVary, "Accept")
+
+	renderable := make([]string, 0, len(offers))
+	for _, offer := range offers {
+		if _, ok := negotiateRenders[offer]; ok {
+			renderable = append(renderable, offer)
+		}
+	}
+	offers = renderable
+	offer := c.NegotiateFormat(offers...)
+	r, ok := negotiateRenders[offer]
+	if !ok {
+		c.Text("Not Acceptable, available: "+strings.Join(offers, ", "), http.StatusNotAcceptable)
+		return
+	}
+	if offer == MIMEPlain || offer == MIMEHTML {
+		if _, ok := data.(string); !ok {
+			data = fmt.Sprint(data)
+			if offer == MIMEHTML {
+				data = html.EscapeString(data.(string))
+			}
+		}
+	}
+	c.executeRender(data, c.Writer, r, status)
+}
+
+// NegotiateFormat returns the offer that best matches the Accept header of
+// the request, or "" if none is acceptable.
+func (c *Context) NegotiateFormat(offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	accepts := parseAccept(c.Req.Header.Get("Accept"))
+	if len(accepts) == 0 {
+		return offers[0]
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := acceptQuality(accepts, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+type acceptRange struct {
+	typ, sub string
+	q        float64
+}
+
+// parseAccept parses an Accept header, the result is sorted by specificity
+// so that the first range matching an offer is the one deciding its quality.
+func parseAccept(header string) []acceptRange {
+	if header == "" {
+		return nil
+	}
+	var accepts []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType == "" {
+			continue
+		}
+		if mediaType == "*" {
+			mediaType = "*/*"
+		}
+		slash := strings.Index(mediaType, "/")
+		if slash < 0 {
+			continue
+		}
+		a := acceptRange{typ: mediaType[:slash], sub: mediaType[slash+1:], q: 1}
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.ToLower(kv[0]) == "q" {
+				if q, err := strconv.ParseFloat(kv[1], 64); err == nil && q >= 0 && q <= 1 {
+					a.q = q
+				}
+			}
+		}
+		accepts = append(accepts, a)
+	}
+	sort.SliceStable(accepts, func(i, j int) bool {
+		return accepts[i].specificity() > accepts[j].specificity()
+	})
+	return accepts
+}
+
+func (a acceptRange) specificity() int {
+	switch {
+	case a.typ == "*":
+		return 0
+	case a.sub == "*":
+		return 1
+	}
+	return 2
+}
+
+func (a acceptRange) match(typ, sub string) bool {
+	return (a.typ == "*" || a.typ == typ) && (a.sub == "*" || a.sub == sub)
+}
+
+func acceptQuality(accepts []acceptRange, offer string) float64 {
+	offer = strings.ToLower(offer)
+	if i := strings.Index(offer, ";"); i >= 0 {
+		offer = strings.TrimSpace(offer[:i])
+	}
+	slash := strings.Index(offer, "/")
+	if slash < 0 {
+		return 0
+	}
+	typ, sub := offer[:slash], offer[slash+1:]
+	for _, a := range accepts {
+		if a.match(typ, sub) {
+			return a.q
+		}
+	}
+	return 0
+}
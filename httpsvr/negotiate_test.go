@@ -0,0 +1,71 @@
+package httpsvr
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/hydah/golib/httpsvr/render"
+)
+
+func Test_Negotiate(t *testing.T) {
+	m := New()
+	m.GET("/", func(ctx *Context) {
+		ctx.Negotiate(http.StatusOK, ExampleXml{One: "hello", Two: "xml"})
+	})
+	m.GET("/text", func(ctx *Context) {
+		ctx.Negotiate(http.StatusOK, "hello", MIMEJSON, MIMEPlain)
+	})
+	m.GET("/html", func(ctx *Context) {
+		ctx.Negotiate(http.StatusOK, []string{"<b>", "&"}, MIMEJSON, MIMEHTML)
+	})
+	m.GET("/jsonp", func(ctx *Context) {
+		ctx.Negotiate(http.StatusOK, "hello", MIMEJSONP, MIMEJSON)
+	})
+
+	Convey("No Accept picks the first offer", t, func() {
+		w := acceptRequest(m, "/", "")
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Header().Get(render.ContentType), ShouldEqual, render.ContentJSON)
+		So(w.Header().Get("Vary"), ShouldEqual, "Accept")
+	})
+	Convey("Highest q-value wins", t, func() {
+		w := acceptRequest(m, "/", "application/json;q=0.5, application/xml")
+		So(w.Header().Get(render.ContentType), ShouldEqual, render.ContentXML)
+		w = acceptRequest(m, "/text", "text/*;q=0.9, */*;q=0.1")
+		So(w.Header().Get(render.ContentType), ShouldEqual, render.ContentPlain)
+		So(w.Body.String(), ShouldEqual, "hello")
+	})
+	Convey("Data other than strings is escaped in HTML", t, func() {
+		w := acceptRequest(m, "/html", "text/html")
+		So(w.Header().Get(render.ContentType), ShouldStartWith, MIMEHTML)
+		So(w.Body.String(), ShouldEqual, "[&lt;b&gt; &amp;]")
+	})
+	Convey("More specific ranges override wildcards", t, func() {
+		w := acceptRequest(m, "/", "*/*, application/json;q=0")
+		So(w.Header().Get(render.ContentType), ShouldEqual, render.ContentXML)
+	})
+	Convey("Offers without a renderer are skipped", t, func() {
+		w := acceptRequest(m, "/jsonp", "application/javascript, application/json;q=0.5")
+		So(w.Code, ShouldEqual, http.StatusOK)
+		So(w.Header().Get(render.ContentType), ShouldEqual, render.ContentJSON)
+		w = acceptRequest(m, "/jsonp", "")
+		So(w.Header().Get(render.ContentType), ShouldEqual, render.ContentJSON)
+	})
+	Convey("Nothing acceptable", t, func() {
+		w := acceptRequest(m, "/", "image/png")
+		So(w.Code, ShouldEqual, http.StatusNotAcceptable)
+	})
+}
+
+func acceptRequest(m *Engine, path, accept string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	return w
+}
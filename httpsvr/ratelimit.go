@@ -0,0 +1,229 @@
+package httpsvr
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hydah/golib/logger"
+	lru "github.com/hydah/golib/utils/lru/sync-lru"
+)
+
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRetryAfter         = "Retry-After"
+
+	// DefaultRateLimitKeys is the number of clients the memory stores
+	// remember, the least recently seen are forgotten first.
+	DefaultRateLimitKeys = 10000
+)
+
+// RateLimitResult is the state of a client after taking one request.
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the client has its whole limit again.
+	Reset time.Duration
+	// RetryAfter is the time until a denied client may try again.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the state of the clients and implements the rate
+// limit algorithm. Implement it on a shared backend to limit the clients
+// across instances.
+type RateLimitStore interface {
+	// Take takes one request of key, allowing limit requests per period.
+	Take(key string, limit int, period time.Duration, now time.Time) (RateLimitResult, error)
+}
+
+// RateLimitOptions configures RateLimit.
+type RateLimitOptions struct {
+	// Limit requests are allowed per Period.
+	Limit  int
+	Period time.Duration
+	// Key identifies the client, default Context.ClientIP, see KeyByHeader.
+	// Requests with an empty key are not limited.
+	Key func(*Context) string
+	// Store defaults to NewTokenBucketStore(DefaultRateLimitKeys).
+	Store RateLimitStore
+	// Denied handles the denied requests, default a 429 text.
+	Denied HandlerFunc
+}
+
+// RateLimit returns a middleware limiting every client to opts.Limit requests
+// per opts.Period. It sets the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers, and Retry-After on denied requests.
+// Limits per route are set by using it on a RouterGroup, each RateLimit
+// keeps its own state unless they share a Store.
+// A failing Store lets the requests through.
+func RateLimit(opts RateLimitOptions) HandlerFunc {
+	if opts.Limit <= 0 || opts.Period <= 0 {
+		panic("httpsvr: RateLimit needs a positive Limit and Period")
+	}
+	if opts.Key == nil {
+		opts.Key = func(ctx *Context) string { return ctx.ClientIP() }
+	}
+	if opts.Store == nil {
+		opts.Store = NewTokenBucketStore(DefaultRateLimitKeys)
+	}
+	if opts.Denied == nil {
+		opts.Denied = func(ctx *Context) {
+			ctx.Text(http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		}
+	}
+	return func(ctx *Context) {
+		key := opts.Key(ctx)
+		if key == "" {
+			return
+		}
+		res, err := opts.Store.Take(key, opts.Limit, opts.Period, time.Now())
+		if err != nil {
+			logger.Error("[%s] rate limit %s: %v", ctx.Engine.AppName, key, err)
+			return
+		}
+		h := ctx.Writer.Header()
+		h.Set(HeaderRateLimitLimit, strconv.Itoa(res.Limit))
+		h.Set(HeaderRateLimitRemaining, strconv.Itoa(res.Remaining))
+		h.Set(HeaderRateLimitReset, seconds(res.Reset))
+		if !res.Allowed {
+			h.Set(HeaderRetryAfter, seconds(res.RetryAfter))
+			opts.Denied(ctx)
+			ctx.Abort()
+		}
+	}
+}
+
+// KeyByHeader returns a RateLimit key function reading the header, e.g. an
+// API key.
+func KeyByHeader(name string) func(*Context) string {
+	return func(ctx *Context) string {
+		return ctx.Req.Header.Get(name)
+	}
+}
+
+// seconds rounds d up to whole seconds.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// lruStore keeps the state of the most recently seen clients.
+type lruStore struct {
+	lock  sync.Mutex
+	cache *lru.Cache
+}
+
+func newLRUStore(size int) lruStore {
+	cache, err := lru.New(size)
+	if err != nil {
+		panic(err)
+	}
+	return lruStore{cache: cache}
+}
+
+type tokenBucketStore struct {
+	lruStore
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucketStore returns a RateLimitStore keeping a bucket of limit
+// tokens per client, refilled at limit per period, for up to size clients.
+// It allows bursts of up to limit requests.
+func NewTokenBucketStore(size int) RateLimitStore {
+	return &tokenBucketStore{newLRUStore(size)}
+}
+
+func (s *tokenBucketStore) Take(key string, limit int, period time.Duration, now time.Time) (RateLimitResult, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	rate := float64(limit) / float64(period)
+	b := &bucket{tokens: float64(limit), last: now}
+	if v, ok := s.cache.Get(key); ok {
+		b = v.(*bucket)
+		b.tokens = math.Min(float64(limit), b.tokens+float64(now.Sub(b.last))*rate)
+		b.last = now
+	} else {
+		s.cache.Add(key, b)
+	}
+
+	res := RateLimitResult{Limit: limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - b.tokens) / rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = time.Duration((float64(limit) - b.tokens) / rate)
+	return res, nil
+}
+
+type slidingWindowStore struct {
+	lruStore
+}
+
+type window struct {
+	start time.Time
+	prev  int
+	curr  int
+}
+
+// NewSlidingWindowStore returns a RateLimitStore counting the requests of
+// each client over a sliding period, for up to size clients. The count is
+// estimated from the current and the previous fixed windows, weighting the
+// previous one by its overlap with the sliding period.
+func NewSlidingWindowStore(size int) RateLimitStore {
+	return &slidingWindowStore{newLRUStore(size)}
+}
+
+func (s *slidingWindowStore) Take(key string, limit int, period time.Duration, now time.Time) (RateLimitResult, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	start := now.Truncate(period)
+	w := &window{start: start}
+	if v, ok := s.cache.Get(key); ok {
+		w = v.(*window)
+	} else {
+		s.cache.Add(key, w)
+	}
+	switch elapsed := start.Sub(w.start); {
+	case elapsed == period:
+		w.start, w.prev, w.curr = start, w.curr, 0
+	case elapsed > period:
+		w.start, w.prev, w.curr = start, 0, 0
+	}
+
+	weight := 1 - float64(now.Sub(start))/float64(period)
+	count := float64(w.prev)*weight + float64(w.curr)
+	res := RateLimitResult{Limit: limit, Reset: start.Add(period).Sub(now)}
+	if count+1 <= float64(limit) {
+		w.curr++
+		count++
+		res.Allowed = true
+	} else {
+		// wait until enough of the previous window slid out, or for the
+		// next window
+		res.RetryAfter = res.Reset
+		if w.prev > 0 {
+			wait := time.Duration((count + 1 - float64(limit)) / float64(w.prev) * float64(period))
+			if wait < res.RetryAfter {
+				res.RetryAfter = wait
+			}
+		}
+	}
+	res.Remaining = limit - int(math.Ceil(count))
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+	return res, nil
+}
@@ -0,0 +1,94 @@
+package httpsvr
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_RateLimitStores(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	Convey("Token bucket allows bursts and refills steadily", t, func() {
+		s := NewTokenBucketStore(10)
+		for i := 0; i < 3; i++ {
+			res, _ := s.Take("a", 3, time.Second, now)
+			So(res.Allowed, ShouldBeTrue)
+			So(res.Remaining, ShouldEqual, 2-i)
+		}
+		res, _ := s.Take("a", 3, time.Second, now)
+		So(res.Allowed, ShouldBeFalse)
+		So(res.RetryAfter, ShouldEqual, time.Second/3)
+		So(res.Reset, ShouldEqual, time.Second)
+
+		res, _ = s.Take("b", 3, time.Second, now)
+		So(res.Allowed, ShouldBeTrue)
+
+		res, _ = s.Take("a", 3, time.Second, now.Add(400*time.Millisecond))
+		So(res.Allowed, ShouldBeTrue)
+		So(res.Remaining, ShouldEqual, 0)
+	})
+
+	Convey("Sliding window weights the previous window", t, func() {
+		s := NewSlidingWindowStore(10)
+		for i := 0; i < 4; i++ {
+			res, _ := s.Take("a", 4, time.Minute, now)
+			So(res.Allowed, ShouldBeTrue)
+		}
+		res, _ := s.Take("a", 4, time.Minute, now.Add(30*time.Second))
+		So(res.Allowed, ShouldBeFalse)
+		So(res.Reset, ShouldEqual, 30*time.Second)
+
+		// 4 requests in the previous window count for 3 at a quarter in
+		res, _ = s.Take("a", 4, time.Minute, now.Add(75*time.Second))
+		So(res.Allowed, ShouldBeTrue)
+		So(res.Remaining, ShouldEqual, 0)
+		res, _ = s.Take("a", 4, time.Minute, now.Add(75*time.Second))
+		So(res.Allowed, ShouldBeFalse)
+		So(res.RetryAfter, ShouldEqual, 15*time.Second)
+
+		res, _ = s.Take("a", 4, time.Minute, now.Add(3*time.Minute))
+		So(res.Allowed, ShouldBeTrue)
+		So(res.Remaining, ShouldEqual, 3)
+	})
+
+	Convey("Memory stores forget the least recently seen clients", t, func() {
+		s := NewTokenBucketStore(2)
+		s.Take("a", 1, time.Hour, now)
+		s.Take("b", 1, time.Hour, now)
+		s.Take("c", 1, time.Hour, now)
+		res, _ := s.Take("a", 1, time.Hour, now)
+		So(res.Allowed, ShouldBeTrue)
+	})
+}
+
+func Test_RateLimit(t *testing.T) {
+	Convey("RateLimit denies with 429 and the rate limit headers", t, func() {
+		e := New()
+		e.Group("/api", func(api *RouterGroup) {
+			api.Use(RateLimit(RateLimitOptions{Limit: 2, Period: time.Minute, Key: KeyByHeader("X-Api-Key")}))
+			api.GET("/ping", func(ctx *Context) { ctx.Text("pong") })
+		})
+		e.GET("/free", func(ctx *Context) { ctx.Text("free") })
+
+		k1, k2 := http.Header{"X-Api-Key": {"k1"}}, http.Header{"X-Api-Key": {"k2"}}
+		w := performRequestWith(e, "GET", "/api/ping", k1, nil)
+		So(w.Code, ShouldEqual, 200)
+		So(w.Header().Get(HeaderRateLimitLimit), ShouldEqual, "2")
+		So(w.Header().Get(HeaderRateLimitRemaining), ShouldEqual, "1")
+		So(performRequestWith(e, "GET", "/api/ping", k1, nil).Code, ShouldEqual, 200)
+
+		w = performRequestWith(e, "GET", "/api/ping", k1, nil)
+		So(w.Code, ShouldEqual, http.StatusTooManyRequests)
+		So(w.Header().Get(HeaderRetryAfter), ShouldEqual, "30")
+		So(w.Header().Get(HeaderRateLimitRemaining), ShouldEqual, "0")
+
+		So(performRequestWith(e, "GET", "/api/ping", k2, nil).Code, ShouldEqual, 200)
+		So(performRequest(e, "GET", "/api/ping").Code, ShouldEqual, 200)
+		w = performRequestWith(e, "GET", "/free", k1, nil)
+		So(w.Code, ShouldEqual, 200)
+		So(w.Header().Get(HeaderRateLimitLimit), ShouldEqual, "")
+	})
+}
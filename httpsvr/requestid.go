@@ -0,0 +1,76 @@
+package httpsvr
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/hydah/golib/logger"
+)
+
+const (
+	HeaderXRequestID = "X-Request-ID"
+
+	// RequestIDKey is the Context.Keys entry holding the request id.
+	RequestIDKey = "request_id"
+
+	maxRequestIDLength = 128
+)
+
+// RequestID returns a middleware giving every request an id: the incoming
+// X-Request-ID header when it is sane, else one made by generate, default
+// 32 random hex digits. The id is stored in Keys under RequestIDKey, echoed
+// in the X-Request-ID response header and carried by the request context,
+// the records logged through Context.Logger have it.
+func RequestID(generate ...func() string) HandlerFunc {
+	gen := newRequestID
+	if len(generate) > 0 && generate[0] != nil {
+		gen = generate[0]
+	}
+	return func(ctx *Context) {
+		id := ctx.Req.Header.Get(HeaderXRequestID)
+		if !validRequestID(id) {
+			id = gen()
+		}
+		ctx.Set(RequestIDKey, id)
+		ctx.Writer.Header().Set(HeaderXRequestID, id)
+
+		ctx.Req = ctx.Req.WithContext(logger.WithRequestID(ctx.Req.Context(), id))
+		ctx.Next()
+	}
+}
+
+// RequestID returns the id given by the RequestID middleware, or "".
+func (c *Context) RequestID() string {
+	id, _ := c.Keys[RequestIDKey].(string)
+	return id
+}
+
+// Logger returns a logger.Entry tagging the records with the request and
+// trace ids of the request, see RequestID and Tracing. Goroutines started by
+// the handler use logger.Ctx(ctx.Req.Context()). The records logged through
+// the package-level functions of logger do not carry the ids.
+func (c *Context) Logger() logger.Entry {
+	return logger.Ctx(c.Req.Context())
+}
+
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		logger.Error("request id: %v", err)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID keeps ids from the outside short and printable so that they
+// can not forge log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package httpsvr
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/hydah/golib/logger"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type recordWriter struct {
+	lock    sync.Mutex
+	records []*logger.LogRecord
+}
+
+func (w *recordWriter) LogWrite(rec *logger.LogRecord) {
+	w.lock.Lock()
+	w.records = append(w.records, rec)
+	w.lock.Unlock()
+}
+
+func (w *recordWriter) Close() {}
+
+func Test_RequestID(t *testing.T) {
+	Convey("RequestID tags the response, the context and the logs", t, func() {
+		w := &recordWriter{}
+		logger.AddFilter("request_id_test", logger.INFO, w)
+
+		e := New()
+		e.Use(RequestID(func() string { return "generated" }))
+		e.GET("/", func(ctx *Context) {
+			ctx.Logger().Info("handling %s", ctx.Req.URL.Path)
+			ctx.Text(ctx.RequestID())
+		})
+		e.GET("/async", func(ctx *Context) {
+			done := make(chan struct{})
+			go func(reqCtx context.Context) {
+				logger.Ctx(reqCtx).Info("in the background")
+				close(done)
+			}(ctx.Req.Context())
+			<-done
+		})
+
+		rec := performRequestWith(e, "GET", "/", http.Header{HeaderXRequestID: {"abc-123"}}, nil)
+		So(rec.Body.String(), ShouldEqual, "abc-123")
+		So(rec.Header().Get(HeaderXRequestID), ShouldEqual, "abc-123")
+
+		rec = performRequest(e, "GET", "/")
+		So(rec.Body.String(), ShouldEqual, "generated")
+		rec = performRequestWith(e, "GET", "/", http.Header{HeaderXRequestID: {"forged\n[INFO] line"}}, nil)
+		So(rec.Header().Get(HeaderXRequestID), ShouldEqual, "generated")
+
+		So(len(w.records), ShouldEqual, 3)
+		So(w.records[0].RequestID, ShouldEqual, "abc-123")
+		So(w.records[0].Message, ShouldEqual, "handling /")
+
+		logger.Info("after the request")
+		So(w.records[3].RequestID, ShouldEqual, "")
+
+		performRequestWith(e, "GET", "/async", http.Header{HeaderXRequestID: {"bg-1"}}, nil)
+		So(w.records[4].RequestID, ShouldEqual, "bg-1")
+		So(w.records[4].Message, ShouldEqual, "in the background")
+	})
+
+	Convey("Generated ids are random hex", t, func() {
+		a, b := newRequestID(), newRequestID()
+		So(len(a), ShouldEqual, 32)
+		So(a, ShouldNotEqual, b)
+	})
+}
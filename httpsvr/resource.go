@@ -0,0 +1,255 @@
+package httpsvr
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// The actions of a resource controller, a controller implements those it
+// supports, see RouterGroup.Resource.
+type (
+	// ResourceIndex lists the resources, GET and HEAD /users.
+	ResourceIndex interface {
+		Index(ctx *Context)
+	}
+	// ResourceShow shows one resource, GET and HEAD /users/:id.
+	ResourceShow interface {
+		Show(ctx *Context)
+	}
+	// ResourceCreate creates a resource, POST /users.
+	ResourceCreate interface {
+		Create(ctx *Context)
+	}
+	// ResourceUpdate replaces a resource, PUT /users/:id, and updates it on
+	// PATCH unless the controller is a ResourcePartialUpdate.
+	ResourceUpdate interface {
+		Update(ctx *Context)
+	}
+	// ResourcePartialUpdate updates a resource, PATCH /users/:id.
+	ResourcePartialUpdate interface {
+		PartialUpdate(ctx *Context)
+	}
+	// ResourceDestroy deletes a resource, DELETE /users/:id.
+	ResourceDestroy interface {
+		Destroy(ctx *Context)
+	}
+)
+
+type resourceAction struct {
+	method string
+	name   string
+	member bool
+	call   func(IController, *Context)
+}
+
+// resourceActions returns the actions ctrl implements.
+func resourceActions(ctrl IController) []resourceAction {
+	var actions []resourceAction
+	if _, ok := ctrl.(ResourceIndex); ok {
+		index := func(c IController, ctx *Context) { c.(ResourceIndex).Index(ctx) }
+		actions = append(actions, resourceAction{"GET", "Index", false, index}, resourceAction{"HEAD", "Index", false, index})
+	}
+	if _, ok := ctrl.(ResourceCreate); ok {
+		actions = append(actions, resourceAction{"POST", "Create", false, func(c IController, ctx *Context) { c.(ResourceCreate).Create(ctx) }})
+	}
+	if _, ok := ctrl.(ResourceShow); ok {
+		show := func(c IController, ctx *Context) { c.(ResourceShow).Show(ctx) }
+		actions = append(actions, resourceAction{"GET", "Show", true, show}, resourceAction{"HEAD", "Show", true, show})
+	}
+	if _, ok := ctrl.(ResourceUpdate); ok {
+		actions = append(actions, resourceAction{"PUT", "Update", true, func(c IController, ctx *Context) { c.(ResourceUpdate).Update(ctx) }})
+	}
+	if _, ok := ctrl.(ResourcePartialUpdate); ok {
+		actions = append(actions, resourceAction{"PATCH", "PartialUpdate", true, func(c IController, ctx *Context) { c.(ResourcePartialUpdate).PartialUpdate(ctx) }})
+	} else if _, ok := ctrl.(ResourceUpdate); ok {
+		actions = append(actions, resourceAction{"PATCH", "Update", true, func(c IController, ctx *Context) { c.(ResourceUpdate).Update(ctx) }})
+	}
+	if _, ok := ctrl.(ResourceDestroy); ok {
+		actions = append(actions, resourceAction{"DELETE", "Destroy", true, func(c IController, ctx *Context) { c.(ResourceDestroy).Destroy(ctx) }})
+	}
+	return actions
+}
+
+// Resource maps the actions ctrl implements, see ResourceIndex and the
+// others, on relativePath and relativePath/:id in one call. The methods of
+// no action get a 405 with the Allow header, and OPTIONS answers with it.
+//
+// Resource returns the group of the members, relativePath/:id, to nest
+// resources:
+//
+//	users := api.Resource("/users", &UsersController{})
+//	users.Resource("/posts", &PostsController{})
+//
+// In nested resources :id is the id of the innermost resource, the parent
+// ids are named after their path without a final "s", e.g. user_id for
+// /users/:user_id/posts/:id, as Routes and URLFor have them, or as
+// ResourceWith names them. The routes
+// added with Handle and the like to the group of a nested resource do not
+// get the renames, their ids are :id, :id1 and so on by depth.
+//
+// The controllers are zero values of the type ctrl points to, as with
+// HandleController.
+func (c *RouterGroup) Resource(relativePath string, ctrl IController, handlers ...HandlerFunc) *RouterGroup {
+	return c.resource(relativePath, ctrl, newZeroPool(ctrl), ResourceOptions{}, handlers)
+}
+
+// ResourceOptions configures ResourceWith.
+type ResourceOptions struct {
+	// IDName names the id of the resource in the paths of its nested
+	// resources, e.g. "person_id" for /people. Default the last segment of
+	// the path without a final "s", followed by "_id".
+	IDName string
+	// Factory makes the controllers instead, see ResourceFunc.
+	Factory ControllerFactory
+}
+
+// ResourceWith is Resource configured by opts, ctrl may be nil when
+// opts.Factory is set.
+func (c *RouterGroup) ResourceWith(relativePath string, ctrl IController, opts ResourceOptions, handlers ...HandlerFunc) *RouterGroup {
+	if opts.Factory == nil {
+		return c.resource(relativePath, ctrl, newZeroPool(ctrl), opts, handlers)
+	}
+	pool := newFactoryPool(opts.Factory)
+	ctrl = pool.get()
+	defer pool.put(ctrl)
+	return c.resource(relativePath, ctrl, pool, opts, handlers)
+}
+
+// ResourceFunc is Resource with the controllers made by factory, see
+// HandleControllerFunc.
+func (c *RouterGroup) ResourceFunc(relativePath string, factory ControllerFactory, handlers ...HandlerFunc) *RouterGroup {
+	return c.ResourceWith(relativePath, nil, ResourceOptions{Factory: factory}, handlers...)
+}
+
+func (c *RouterGroup) resource(relativePath string, ctrl IController, pool *controllerPool, opts ResourceOptions, handlers []HandlerFunc) *RouterGroup {
+	if opts.IDName == "" {
+		name := strings.Trim(relativePath, "/")
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			name = name[i+1:]
+		}
+		opts.IDName = strings.TrimSuffix(name, "s") + "_id"
+	}
+	// the router wants the same wildcard name at the same position for
+	// every route, so nested ids get unique names which the handlers rename
+	key := resourceKey(len(c.resourceIDs))
+	renames := make(map[string]string, len(c.resourceIDs)+1)
+	for i, id := range c.resourceIDs {
+		renames[resourceKey(i)] = id
+	}
+	renames[key] = "id"
+
+	collection := c.calculateAbsolutePath(relativePath)
+	members := &RouterGroup{
+		Handlers:     c.combineHandlers(handlers),
+		absolutePath: strings.TrimSuffix(collection, "/") + "/:" + key,
+		engine:       c.engine,
+		resourceIDs:  append(append([]string(nil), c.resourceIDs...), opts.IDName),
+	}
+
+	member := strings.TrimSuffix(relativePath, "/") + "/:" + key
+	handlers = handlers[:len(handlers):len(handlers)]
+	var allowCollection, allowMember []string
+	for _, action := range resourceActions(ctrl) {
+		path := relativePath
+		if action.member {
+			path = member
+			allowMember = append(allowMember, action.method)
+		} else {
+			allowCollection = append(allowCollection, action.method)
+		}
+		call := action.call
+		route := c.Handle(action.method, path, append(handlers, func(ctx *Context) {
+			renameParams(ctx, renames)
+			runController(ctx, pool, call)
+		}))
+		route.info.Path = renamePath(route.info.Path, renames)
+		route.info.Handler = actionName(ctrl, action.name)
+	}
+	if len(allowCollection) > 0 {
+		route := c.Handle("OPTIONS", relativePath, append(handlers, allowHandler(allowCollection)))
+		route.info.Path = renamePath(route.info.Path, renames)
+	}
+	if len(allowMember) > 0 {
+		route := c.Handle("OPTIONS", member, append(handlers, allowHandler(allowMember)))
+		route.info.Path = renamePath(route.info.Path, renames)
+	}
+	return members
+}
+
+// actionName names the action method of ctrl as nameOfFunction names the
+// methods, e.g. "github.com/app/api.(*UsersController).Show".
+func actionName(ctrl IController, action string) string {
+	t := reflect.TypeOf(ctrl)
+	if t.Kind() == reflect.Ptr {
+		return t.Elem().PkgPath() + ".(*" + t.Elem().Name() + ")." + action
+	}
+	return t.PkgPath() + "." + t.Name() + "." + action
+}
+
+// resourceKey is the wildcard of the resource id at depth.
+func resourceKey(depth int) string {
+	if depth == 0 {
+		return "id"
+	}
+	return "id" + strconv.Itoa(depth)
+}
+
+// renameParams gives the resource ids their names.
+func renameParams(ctx *Context, renames map[string]string) {
+	params := make(httprouter.Params, len(ctx.Params))
+	for i, p := range ctx.Params {
+		if name, ok := renames[p.Key]; ok {
+			p.Key = name
+		}
+		params[i] = p
+	}
+	ctx.Params = params
+}
+
+// renamePath gives the resource ids of a route path their names.
+func renamePath(path string, renames map[string]string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") {
+			continue
+		}
+		if name, ok := renames[segment[1:]]; ok {
+			segments[i] = ":" + name
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func allowHandler(methods []string) HandlerFunc {
+	allow := strings.Join(append(methods, "OPTIONS"), ", ")
+	return func(ctx *Context) {
+		ctx.SetHeader("Allow", allow)
+		ctx.Writer.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// controllerMethod returns the IController method of the HTTP method, or
+// nil.
+func controllerMethod(ctrl IController, method string) HandlerFunc {
+	switch method {
+	case "GET":
+		return ctrl.Get
+	case "POST":
+		return ctrl.Post
+	case "DELETE":
+		return ctrl.Delete
+	case "PATCH":
+		return ctrl.Patch
+	case "PUT":
+		return ctrl.Put
+	case "OPTIONS":
+		return ctrl.Options
+	case "HEAD":
+		return ctrl.Head
+	}
+	return nil
+}
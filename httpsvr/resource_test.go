@@ -0,0 +1,102 @@
+package httpsvr
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type usersController struct {
+	Controller
+}
+
+func (c *usersController) Index(ctx *Context)  { ctx.Text("index") }
+func (c *usersController) Show(ctx *Context)   { ctx.Text("show " + ctx.Params.ByName("id")) }
+func (c *usersController) Create(ctx *Context) { ctx.Text("create", http.StatusCreated) }
+func (c *usersController) Update(ctx *Context) { ctx.Text("update " + ctx.Params.ByName("id")) }
+func (c *usersController) Destroy(ctx *Context) {
+	ctx.Writer.WriteHeader(http.StatusNoContent)
+}
+
+type postsController struct {
+	Controller
+}
+
+func (c *postsController) Show(ctx *Context) {
+	ctx.Text("post " + ctx.Params.ByName("id") + " of " + ctx.Params.ByName("user_id"))
+}
+func (c *postsController) PartialUpdate(ctx *Context) { ctx.Text("patch") }
+
+type deleteController struct {
+	Controller
+}
+
+func (c *deleteController) Delete(ctx *Context) { ctx.Text("deleted") }
+
+func Test_Resource(t *testing.T) {
+	Convey("Resource maps the actions on the verbs", t, func() {
+		e := New()
+		users := e.Resource("/users", &usersController{})
+
+		So(performRequest(e, "GET", "/users").Body.String(), ShouldEqual, "index")
+		So(performRequest(e, "HEAD", "/users").Code, ShouldEqual, 200)
+		w := performRequest(e, "POST", "/users")
+		So(w.Code, ShouldEqual, http.StatusCreated)
+		So(w.Body.String(), ShouldEqual, "create")
+		So(performRequest(e, "GET", "/users/7").Body.String(), ShouldEqual, "show 7")
+		So(performRequest(e, "PUT", "/users/7").Body.String(), ShouldEqual, "update 7")
+		So(performRequest(e, "PATCH", "/users/7").Body.String(), ShouldEqual, "update 7")
+		So(performRequest(e, "DELETE", "/users/7").Code, ShouldEqual, http.StatusNoContent)
+
+		w = performRequest(e, "DELETE", "/users")
+		So(w.Code, ShouldEqual, http.StatusMethodNotAllowed)
+		So(w.Header().Get("Allow"), ShouldContainSubstring, "POST")
+
+		w = performRequest(e, "OPTIONS", "/users/7")
+		So(w.Code, ShouldEqual, http.StatusNoContent)
+		So(w.Header().Get("Allow"), ShouldEqual, "GET, HEAD, PUT, PATCH, DELETE, OPTIONS")
+
+		Convey("and nests resources", func() {
+			users.Resource("/posts", &postsController{})
+			So(performRequest(e, "GET", "/users/7/posts/3").Body.String(), ShouldEqual, "post 3 of 7")
+			So(performRequest(e, "PATCH", "/users/7/posts/3").Body.String(), ShouldEqual, "patch")
+			So(performRequest(e, "GET", "/users/7").Body.String(), ShouldEqual, "show 7")
+
+			w := performRequest(e, "PUT", "/users/7/posts/3")
+			So(w.Code, ShouldEqual, http.StatusMethodNotAllowed)
+			So(w.Header().Get("Allow"), ShouldContainSubstring, "PATCH")
+			So(performRequest(e, "GET", "/users/7/posts").Code, ShouldEqual, http.StatusNotFound)
+
+			var paths []string
+			for _, route := range e.Routes() {
+				if route.Method == "GET" {
+					paths = append(paths, route.Path)
+				}
+			}
+			So(paths, ShouldResemble, []string{"/users", "/users/:id", "/users/:user_id/posts/:id"})
+			handlers := map[string]string{}
+			for _, route := range e.Routes() {
+				handlers[route.Method+" "+route.Path] = route.Handler
+			}
+			So(handlers["PATCH /users/:id"], ShouldEqual, "github.com/hydah/golib/httpsvr.(*usersController).Update")
+			So(handlers["PATCH /users/:user_id/posts/:id"], ShouldEqual, "github.com/hydah/golib/httpsvr.(*postsController).PartialUpdate")
+		})
+
+		Convey("and names the parent ids as asked", func() {
+			e := New()
+			people := e.ResourceWith("/people", &usersController{}, ResourceOptions{IDName: "user_id"})
+			people.Resource("/posts", &postsController{})
+			So(performRequest(e, "GET", "/people/7/posts/3").Body.String(), ShouldEqual, "post 3 of 7")
+			So(e.Routes()[len(e.Routes())-1].Path, ShouldStartWith, "/people/:user_id/posts")
+		})
+	})
+
+	Convey("Controllers dispatch DELETE", t, func() {
+		s := NewHTTPServer()
+		s.AddRoute("DELETE", "/items/:id", &deleteController{})
+		s.AddRoute("TRACE", "/items/:id", &deleteController{})
+		So(performRequest(s.engine, "DELETE", "/items/1").Body.String(), ShouldEqual, "deleted")
+		So(performRequest(s.engine, "GET", "/items/1").Code, ShouldEqual, http.StatusMethodNotAllowed)
+	})
+}
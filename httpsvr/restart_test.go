@@ -0,0 +1,94 @@
+//go:build !windows
+// +build !windows
+
+package httpsvr
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const restartHelperEnv = "HTTPSVR_TEST_RESTART_ADDR"
+
+// Test_RestartHelper is the server process forked by Test_Restart.
+func Test_RestartHelper(t *testing.T) {
+	addr := os.Getenv(restartHelperEnv)
+	if addr == "" {
+		return
+	}
+	s := NewHTTPServer()
+	s.EnableHijactSignal()
+	s.engine.GET("/pid", func(ctx *Context) {
+		ctx.Text(strconv.Itoa(os.Getpid()))
+	})
+	s.Run(addr)
+	os.Exit(0)
+}
+
+func Test_Restart(t *testing.T) {
+	if os.Getenv(restartHelperEnv) != "" {
+		return
+	}
+	Convey("SIGUSR2 hands the listener to a new process", t, func() {
+		addr := freeAddr()
+		cmd := exec.Command(os.Args[0], "-test.run=^Test_RestartHelper$")
+		cmd.Env = append(os.Environ(), restartHelperEnv+"="+addr)
+		So(cmd.Start(), ShouldBeNil)
+		waitListening(addr)
+
+		client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+		getPid := func() (string, error) {
+			resp, err := client.Get("http://" + addr + "/pid")
+			if err != nil {
+				return "", err
+			}
+			defer resp.Body.Close()
+			b, err := ioutil.ReadAll(resp.Body)
+			return string(b), err
+		}
+
+		oldPid := strconv.Itoa(cmd.Process.Pid)
+		pid, err := getPid()
+		So(err, ShouldBeNil)
+		So(pid, ShouldEqual, oldPid)
+
+		So(cmd.Process.Signal(syscall.SIGUSR2), ShouldBeNil)
+		exited := make(chan error, 1)
+		go func() { exited <- cmd.Wait() }()
+
+		// every request succeeds while the old process hands over and exits
+		newPid := ""
+		deadline := time.Now().Add(10 * time.Second)
+		for time.Now().Before(deadline) {
+			pid, err := getPid()
+			So(err, ShouldBeNil)
+			if pid != oldPid {
+				newPid = pid
+				break
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		So(newPid, ShouldNotBeEmpty)
+
+		select {
+		case err := <-exited:
+			So(err, ShouldBeNil)
+		case <-time.After(5 * time.Second):
+			So("old process did not exit", ShouldBeEmpty)
+		}
+		pid, err = getPid()
+		So(err, ShouldBeNil)
+		So(pid, ShouldEqual, newPid)
+
+		n, _ := strconv.Atoi(newPid)
+		syscall.Kill(n, syscall.SIGTERM)
+	})
+}
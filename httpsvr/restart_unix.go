@@ -0,0 +1,96 @@
+//go:build !windows
+// +build !windows
+
+package httpsvr
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+
+	"github.com/hydah/golib/logger"
+)
+
+// restartSignal makes the server Restart, see EnableHijactSignal.
+var restartSignal os.Signal = syscall.SIGUSR2
+
+// Restart starts a new process of the same executable with the same
+// arguments, which inherits the listeners through the environment. Once the
+// new process serves on all of them it sends SIGTERM to this process, which
+// then drains its in-flight requests and shuts down.
+func (s *HTTPServer) Restart() error {
+	path, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	// Hold the lock so that the listeners stay open until the fork is done.
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closing {
+		return http.ErrServerClosed
+	}
+	// The descriptors are passed with syscall.ForkExec as os/exec would
+	// switch the shared sockets to blocking mode.
+	files := []uintptr{os.Stdin.Fd(), os.Stdout.Fd(), os.Stderr.Fd()}
+	for _, ln := range s.listeners {
+		fd, err := listenerFD(ln)
+		if err != nil {
+			return err
+		}
+		files = append(files, fd)
+	}
+	env := append(os.Environ(),
+		envListenFDs+"="+strconv.Itoa(len(s.listeners)),
+		envParentPID+"="+strconv.Itoa(os.Getpid()),
+	)
+	pid, err := syscall.ForkExec(path, os.Args, &syscall.ProcAttr{Env: env, Files: files})
+	if err != nil {
+		return err
+	}
+	for _, ln := range s.listeners {
+		keepUnixSocket(ln)
+	}
+	logger.Info("[%s] restarting, new process %d", s.engine.AppName, pid)
+	if p, err := os.FindProcess(pid); err == nil {
+		go p.Wait()
+	}
+	return nil
+}
+
+// notifyParent tells the restarting parent process to shut down once every
+// listener it passed is served.
+func notifyParent() {
+	inherited.lock.Lock()
+	defer inherited.lock.Unlock()
+	if inherited.parentPID <= 0 || inherited.claimed < inherited.total {
+		return
+	}
+	if err := syscall.Kill(inherited.parentPID, syscall.SIGTERM); err != nil {
+		logger.Error("notify parent %d: %v", inherited.parentPID, err)
+	}
+	inherited.parentPID = 0
+}
+
+// listenerFD returns the file descriptor of ln, it is valid while ln is open.
+func listenerFD(ln net.Listener) (uintptr, error) {
+	if l, ok := ln.(*onceCloseListener); ok {
+		ln = l.Listener
+	}
+	sc, ok := ln.(syscall.Conn)
+	if !ok {
+		return 0, errors.New("listener " + ln.Addr().String() + " can not be passed to a new process")
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var fd uintptr
+	if err := raw.Control(func(f uintptr) { fd = f }); err != nil {
+		return 0, err
+	}
+	return fd, nil
+}
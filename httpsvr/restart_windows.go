@@ -0,0 +1,18 @@
+package httpsvr
+
+import (
+	"errors"
+	"os"
+)
+
+// restartSignal is nil, there is no SIGUSR2 on windows.
+var restartSignal os.Signal
+
+// Restart is not supported on windows, the listeners can not be passed to
+// a new process.
+func (s *HTTPServer) Restart() error {
+	return errors.New("httpsvr: restart is not supported on windows")
+}
+
+// notifyParent does nothing, no parent process restarts on windows.
+func notifyParent() {}
@@ -0,0 +1,8 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package httpsvr
+
+import "syscall"
+
+const soReusePort = syscall.SO_REUSEPORT
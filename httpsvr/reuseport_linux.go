@@ -0,0 +1,4 @@
+package httpsvr
+
+// soReusePort is SO_REUSEPORT, which package syscall does not define on linux.
+const soReusePort = 0xf
@@ -3,7 +3,6 @@ package httpsvr
 import (
 	"net/http"
 	"path"
-	"reflect"
 
 	"github.com/julienschmidt/httprouter"
 )
@@ -12,6 +11,8 @@ type RouterGroup struct {
 	Handlers     []HandlerFunc
 	absolutePath string
 	engine       *Engine
+	// resourceIDs names the ids of the parent resources, see Resource.
+	resourceIDs []string
 }
 
 // Adds middlewares to the group
@@ -26,6 +27,7 @@ func (c *RouterGroup) Group(relativePath string, fn func(*RouterGroup), handlers
 		Handlers:     c.combineHandlers(handlers),
 		absolutePath: c.calculateAbsolutePath(relativePath),
 		engine:       c.engine,
+		resourceIDs:  c.resourceIDs,
 	}
 	fn(router)
 	return router
@@ -40,50 +42,52 @@ func (c *RouterGroup) Group(relativePath string, fn func(*RouterGroup), handlers
 // This function is intended for bulk loading and to allow the usage of less
 // frequently used, non-standardized or custom methods (e.g. for internal
 // communication with a proxy).
-func (c *RouterGroup) Handle(httpMethod, relativePath string, handlers []HandlerFunc) {
+func (c *RouterGroup) Handle(httpMethod, relativePath string, handlers []HandlerFunc) *Route {
 	absolutePath := c.calculateAbsolutePath(relativePath)
 	handlers = c.combineHandlers(handlers)
 	c.engine.router.Handle(httpMethod, absolutePath, func(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
 		ctx := c.engine.createContext(w, req, params, handlers, nil)
+		ctx.route = absolutePath
 		ctx.Next()
 		ctx.Writer.WriteHeaderNow()
 		c.engine.reuseContext(ctx)
 	})
+	return c.engine.addRoute(httpMethod, absolutePath, handlers)
 }
 
 // POST is a shortcut for router.Handle("POST", path, handle)
-func (c *RouterGroup) POST(relativePath string, handlers ...HandlerFunc) {
-	c.Handle("POST", relativePath, handlers)
+func (c *RouterGroup) POST(relativePath string, handlers ...HandlerFunc) *Route {
+	return c.Handle("POST", relativePath, handlers)
 }
 
 // GET is a shortcut for router.Handle("GET", path, handle)
-func (c *RouterGroup) GET(relativePath string, handlers ...HandlerFunc) {
-	c.Handle("GET", relativePath, handlers)
+func (c *RouterGroup) GET(relativePath string, handlers ...HandlerFunc) *Route {
+	return c.Handle("GET", relativePath, handlers)
 }
 
 // DELETE is a shortcut for router.Handle("DELETE", path, handle)
-func (c *RouterGroup) DELETE(relativePath string, handlers ...HandlerFunc) {
-	c.Handle("DELETE", relativePath, handlers)
+func (c *RouterGroup) DELETE(relativePath string, handlers ...HandlerFunc) *Route {
+	return c.Handle("DELETE", relativePath, handlers)
 }
 
 // PATCH is a shortcut for router.Handle("PATCH", path, handle)
-func (c *RouterGroup) PATCH(relativePath string, handlers ...HandlerFunc) {
-	c.Handle("PATCH", relativePath, handlers)
+func (c *RouterGroup) PATCH(relativePath string, handlers ...HandlerFunc) *Route {
+	return c.Handle("PATCH", relativePath, handlers)
 }
 
 // PUT is a shortcut for router.Handle("PUT", path, handle)
-func (c *RouterGroup) PUT(relativePath string, handlers ...HandlerFunc) {
-	c.Handle("PUT", relativePath, handlers)
+func (c *RouterGroup) PUT(relativePath string, handlers ...HandlerFunc) *Route {
+	return c.Handle("PUT", relativePath, handlers)
 }
 
 // OPTIONS is a shortcut for router.Handle("OPTIONS", path, handle)
-func (c *RouterGroup) OPTIONS(relativePath string, handlers ...HandlerFunc) {
-	c.Handle("OPTIONS", relativePath, handlers)
+func (c *RouterGroup) OPTIONS(relativePath string, handlers ...HandlerFunc) *Route {
+	return c.Handle("OPTIONS", relativePath, handlers)
 }
 
 // HEAD is a shortcut for router.Handle("HEAD", path, handle)
-func (c *RouterGroup) HEAD(relativePath string, handlers ...HandlerFunc) {
-	c.Handle("HEAD", relativePath, handlers)
+func (c *RouterGroup) HEAD(relativePath string, handlers ...HandlerFunc) *Route {
+	return c.Handle("HEAD", relativePath, handlers)
 }
 
 // HandleController registers a new request handle and middlewares with the given path and method.
@@ -95,64 +99,116 @@ func (c *RouterGroup) HEAD(relativePath string, handlers ...HandlerFunc) {
 // This function is intended for bulk loading and to allow the usage of less
 // frequently used, non-standardized or custom methods (e.g. for internal
 // communication with a proxy).
-func (c *RouterGroup) HandleController(httpMethod, relativePath string, ctrl IController, handlers ...HandlerFunc) {
+//
+// The controllers of a request are zero values of the type ctrl points to,
+// see HandleControllerPrototype and HandleControllerFunc to give them
+// dependencies.
+func (c *RouterGroup) HandleController(httpMethod, relativePath string, ctrl IController, handlers ...HandlerFunc) *Route {
+	return c.handleController(httpMethod, relativePath, newZeroPool(ctrl), handlers)
+}
+
+// HandleControllerPrototype is HandleController with the controllers copied
+// from ctrl, a pointer to a struct, e.g. one holding the dependencies of its
+// handlers. The copies are shallow and reused between requests: they are
+// reset with Reset, see Resetter, or with a new copy of ctrl.
+func (c *RouterGroup) HandleControllerPrototype(httpMethod, relativePath string, ctrl IController, handlers ...HandlerFunc) *Route {
+	return c.handleController(httpMethod, relativePath, newPrototypePool(ctrl), handlers)
+}
+
+// HandleControllerFunc is HandleController with the controllers made by
+// factory, those which are Resetters are reused between requests.
+func (c *RouterGroup) HandleControllerFunc(httpMethod, relativePath string, factory ControllerFactory) *Route {
+	pool := newFactoryPool(factory)
+	// the handler holds the place of the controller, it names the route
+	ctrl := pool.get()
+	handler := controllerMethod(ctrl, httpMethod)
+	pool.put(ctrl)
+	if handler == nil {
+		handler = func(*Context) {}
+	}
+	return c.handleController(httpMethod, relativePath, pool, []HandlerFunc{handler})
+}
+
+func (c *RouterGroup) handleController(httpMethod, relativePath string, pool *controllerPool, handlers []HandlerFunc) *Route {
 	absolutePath := c.calculateAbsolutePath(relativePath)
 	handlers = c.combineHandlers(handlers)
-	controllers := c.combineIControllers(ctrl)
+	controllers := c.combineIControllers(pool)
 	c.engine.router.Handle(httpMethod, absolutePath, func(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
 		ctx := c.engine.createContext(w, req, params, handlers, controllers)
+		ctx.route = absolutePath
 		ctx.Next()
 		ctx.Writer.WriteHeaderNow()
 		c.engine.reuseContext(ctx)
 	})
+	return c.engine.addRoute(httpMethod, absolutePath, handlers)
 }
 
 // POSTController is a shortcut for router.Handle("POST", path, handle)
-func (c *RouterGroup) POSTController(relativePath string, ctrl IController) {
-	c.HandleController("POST", relativePath, ctrl, ctrl.Post)
+func (c *RouterGroup) POSTController(relativePath string, ctrl IController) *Route {
+	return c.HandleController("POST", relativePath, ctrl, ctrl.Post)
 }
 
 // GETController is a shortcut for router.Handle("GET", path, handle)
-func (c *RouterGroup) GETController(relativePath string, ctrl IController) {
-	c.HandleController("GET", relativePath, ctrl, ctrl.Get)
+func (c *RouterGroup) GETController(relativePath string, ctrl IController) *Route {
+	return c.HandleController("GET", relativePath, ctrl, ctrl.Get)
 }
 
 // DELETEController is a shortcut for router.Handle("DELETE", path, handle)
-func (c *RouterGroup) DELETEController(relativePath string, ctrl IController) {
-	c.HandleController("DELETE", relativePath, ctrl, ctrl.Delete)
+func (c *RouterGroup) DELETEController(relativePath string, ctrl IController) *Route {
+	return c.HandleController("DELETE", relativePath, ctrl, ctrl.Delete)
 }
 
 // PATCHController is a shortcut for router.Handle("PATCH", path, handle)
-func (c *RouterGroup) PATCHController(relativePath string, ctrl IController) {
-	c.HandleController("PATCH", relativePath, ctrl, ctrl.Patch)
+func (c *RouterGroup) PATCHController(relativePath string, ctrl IController) *Route {
+	return c.HandleController("PATCH", relativePath, ctrl, ctrl.Patch)
 }
 
 // PUTController is a shortcut for router.Handle("PUT", path, handle)
-func (c *RouterGroup) PUTController(relativePath string, ctrl IController) {
-	c.HandleController("PUT", relativePath, ctrl, ctrl.Put)
+func (c *RouterGroup) PUTController(relativePath string, ctrl IController) *Route {
+	return c.HandleController("PUT", relativePath, ctrl, ctrl.Put)
 }
 
 // OPTIONSController is a shortcut for router.Handle("OPTIONS", path, handle)
-func (c *RouterGroup) OPTIONSController(relativePath string, ctrl IController) {
-	c.HandleController("OPTIONS", relativePath, ctrl, ctrl.Options)
+func (c *RouterGroup) OPTIONSController(relativePath string, ctrl IController) *Route {
+	return c.HandleController("OPTIONS", relativePath, ctrl, ctrl.Options)
 }
 
 // HEADController is a shortcut for router.Handle("HEAD", path, handle)
-func (c *RouterGroup) HEADController(relativePath string, ctrl IController) {
-	c.HandleController("HEAD", relativePath, ctrl, ctrl.Head)
+func (c *RouterGroup) HEADController(relativePath string, ctrl IController) *Route {
+	return c.HandleController("HEAD", relativePath, ctrl, ctrl.Head)
+}
+
+// NoRoute registers the handlers answering the paths under the group which
+// match no route, after the group middlewares. They start with a 404 status
+// and the built-in page is written if they write nothing, e.g.
+//
+//	api.NoRoute(func(ctx *Context) { ctx.Json(JSON{"error": "not found"}, 404) })
+//
+// The group with the longest prefix wins. Register them after Use.
+func (c *RouterGroup) NoRoute(handlers ...HandlerFunc) {
+	c.engine.noRoute = addFallback(c.engine.noRoute, c.fallback(handlers))
+}
+
+// NoMethod registers the handlers answering the paths under the group which
+// match a route of another method only, see NoRoute. They start with a 405
+// status and the Allow header set.
+func (c *RouterGroup) NoMethod(handlers ...HandlerFunc) {
+	c.engine.noMethod = addFallback(c.engine.noMethod, c.fallback(handlers))
 }
 
-// Static serves files from the given file system root.
-// Internally a http.FileServer is used, therefore http.NotFound is used instead
-// of the Router's NotFound handler.
+func (c *RouterGroup) fallback(handlers []HandlerFunc) *fallbackRoute {
+	return &fallbackRoute{
+		prefix:   c.absolutePath,
+		handlers: c.combineHandlers(handlers),
+		own:      handlers,
+	}
+}
+
+// Static serves files from the given file system root, see StaticFS.
 // To use the operating system's file system implementation,
 // use : router.Static("/static", "/var/www")
 func (c *RouterGroup) Static(path, dir string) {
-	if lastChar(path) != '/' {
-		path += "/"
-	}
-	path += "*filepath"
-	c.engine.router.ServeFiles(path, http.Dir(dir))
+	c.StaticFS(path, http.Dir(dir))
 }
 
 
@@ -163,15 +219,13 @@ func (c *RouterGroup) combineHandlers(handlers []HandlerFunc) []HandlerFunc {
 	return append(mergedHandlers, handlers...)
 }
 
-func (c *RouterGroup) combineIControllers(ctrl IController) []reflect.Type {
+func (c *RouterGroup) combineIControllers(pool *controllerPool) []*controllerPool {
 	finalSize := len(c.Handlers) + 1
-	rtn := make([]reflect.Type, 0, finalSize)
+	rtn := make([]*controllerPool, 0, finalSize)
 	for i := 0; i < len(c.Handlers); i++ {
 		rtn = append(rtn, nil)
 	}
-	reflectVal := reflect.ValueOf(ctrl)
-	t := reflectVal.Elem().Type()
-	return append(rtn, t)
+	return append(rtn, pool)
 }
 
 func (c *RouterGroup) calculateAbsolutePath(relativePath string) string {
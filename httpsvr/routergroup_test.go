@@ -1,6 +1,7 @@
 package httpsvr
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -26,6 +27,19 @@ func performRequest(r http.Handler, method, path string) *httptest.ResponseRecor
 	r.ServeHTTP(w, req)
 	return w
 }
+
+// performRequestWith is performRequest with the headers and the body of the
+// request.
+func performRequestWith(r http.Handler, method, path string, header http.Header, body io.Reader) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, body)
+	for k, v := range header {
+		req.Header[http.CanonicalHeaderKey(k)] = v
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
 func testRouteOK(method string, t *testing.T) {
 	Convey(method+" Method", t, func() {
 		passed := false
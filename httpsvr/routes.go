@@ -0,0 +1,134 @@
+package httpsvr
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	Method string
+	Path   string
+	Name   string
+	// Handler is the name of the last handler of the chain.
+	Handler string
+	// Middlewares is the number of handlers before it.
+	Middlewares int
+}
+
+// Route is returned by the route registration methods to name the route.
+type Route struct {
+	engine *Engine
+	info   *RouteInfo
+}
+
+// Name names the route for Engine.URLFor, names are unique.
+func (r *Route) Name(name string) *Route {
+	if _, ok := r.engine.namedRoutes[name]; ok {
+		panic("httpsvr: route name " + name + " is already used")
+	}
+	if r.engine.namedRoutes == nil {
+		r.engine.namedRoutes = make(map[string]*RouteInfo)
+	}
+	r.info.Name = name
+	r.engine.namedRoutes[name] = r.info
+	return r
+}
+
+// addRoute records a route registered with httprouter.
+func (c *Engine) addRoute(method, path string, handlers []HandlerFunc) *Route {
+	info := &RouteInfo{Method: method, Path: path, Middlewares: len(handlers) - 1}
+	if len(handlers) > 0 {
+		info.Handler = nameOfFunction(handlers[len(handlers)-1])
+	}
+	c.routes = append(c.routes, info)
+	return &Route{engine: c, info: info}
+}
+
+// Routes returns the registered routes in the order of registration.
+func (c *Engine) Routes() []RouteInfo {
+	routes := make([]RouteInfo, len(c.routes))
+	for i, info := range c.routes {
+		routes[i] = *info
+	}
+	return routes
+}
+
+// URLFor builds the path of the named route, params are pairs of parameter
+// names and values, e.g. URLFor("user", "id", 42). The pairs which are not
+// route parameters go in the query string. It is "urlfor" in templates.
+func (c *Engine) URLFor(name string, params ...interface{}) (string, error) {
+	info, ok := c.namedRoutes[name]
+	if !ok {
+		return "", errors.New("httpsvr: no route named " + name)
+	}
+	if len(params)%2 != 0 {
+		return "", errors.New("httpsvr: URLFor wants pairs of names and values")
+	}
+	values := make(map[string]string, len(params)/2)
+	var order []string
+	for i := 0; i < len(params); i += 2 {
+		key, ok := params[i].(string)
+		if !ok {
+			return "", fmt.Errorf("httpsvr: URLFor parameter name %v is not a string", params[i])
+		}
+		if _, ok := values[key]; !ok {
+			order = append(order, key)
+		}
+		values[key] = fmt.Sprint(params[i+1])
+	}
+
+	segments := strings.Split(info.Path, "/")
+	for i, segment := range segments {
+		if segment == "" || (segment[0] != ':' && segment[0] != '*') {
+			continue
+		}
+		key := segment[1:]
+		value, ok := values[key]
+		if !ok {
+			return "", errors.New("httpsvr: route " + name + " wants parameter " + key)
+		}
+		delete(values, key)
+		if segment[0] == ':' {
+			segments[i] = url.PathEscape(value)
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(value, "/"), "/")
+		for j, part := range parts {
+			parts[j] = url.PathEscape(part)
+		}
+		segments[i] = strings.Join(parts, "/")
+	}
+
+	path := strings.Join(segments, "/")
+	if len(values) > 0 {
+		query := make(url.Values, len(values))
+		for _, key := range order {
+			if value, ok := values[key]; ok {
+				query.Set(key, value)
+			}
+		}
+		path += "?" + query.Encode()
+	}
+	return path, nil
+}
+
+// printRoutes writes the route table, in DEV at startup.
+func (c *Engine) printRoutes() {
+	for _, info := range c.routes {
+		name := ""
+		if info.Name != "" {
+			name = " as " + info.Name
+		}
+		fmt.Printf("[%s] %-7s %-30s --> %s (%d middlewares)%s\n",
+			c.AppName, info.Method, info.Path, info.Handler, info.Middlewares, name)
+	}
+}
+
+func nameOfFunction(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
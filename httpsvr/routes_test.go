@@ -0,0 +1,83 @@
+package httpsvr
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func showUser(ctx *Context) {}
+
+func Test_Routes(t *testing.T) {
+	Convey("Routes lists the registered routes", t, func() {
+		e := New()
+		e.Use(Recovery())
+		e.GET("/users/:id", showUser).Name("user")
+		e.Group("/files", func(g *RouterGroup) {
+			g.Use(func(ctx *Context) {})
+			g.GET("/*path", func(ctx *Context) {}).Name("file")
+		})
+		e.POST("/users", func(ctx *Context) {})
+
+		routes := e.Routes()
+		So(len(routes), ShouldEqual, 3)
+		So(routes[0], ShouldResemble, RouteInfo{
+			Method:      "GET",
+			Path:        "/users/:id",
+			Name:        "user",
+			Handler:     "github.com/hydah/golib/httpsvr.showUser",
+			Middlewares: 1,
+		})
+		So(routes[1].Path, ShouldEqual, "/files/*path")
+		So(routes[1].Middlewares, ShouldEqual, 2)
+		So(routes[2].Method, ShouldEqual, "POST")
+		So(routes[2].Name, ShouldEqual, "")
+
+		So(func() { e.GET("/me", showUser).Name("user") }, ShouldPanic)
+	})
+
+	Convey("URLFor builds the path of named routes", t, func() {
+		e := New()
+		e.GET("/users/:id/posts/:post", showUser).Name("post")
+		e.GET("/files/*path", showUser).Name("file")
+		e.GET("/search", showUser).Name("search")
+
+		url, err := e.URLFor("post", "id", 42, "post", "hello world")
+		So(err, ShouldBeNil)
+		So(url, ShouldEqual, "/users/42/posts/hello%20world")
+
+		url, _ = e.URLFor("file", "path", "/docs/a b.txt")
+		So(url, ShouldEqual, "/files/docs/a%20b.txt")
+
+		url, _ = e.URLFor("search", "q", "go", "page", 2)
+		So(url, ShouldEqual, "/search?page=2&q=go")
+
+		_, err = e.URLFor("post", "id", 42)
+		So(err, ShouldNotBeNil)
+		_, err = e.URLFor("missing")
+		So(err, ShouldNotBeNil)
+		_, err = e.URLFor("post", "id")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Templates reverse routes with urlfor", t, func() {
+		dir, _ := ioutil.TempDir("", "routes")
+		defer os.RemoveAll(dir)
+		ioutil.WriteFile(filepath.Join(dir, "link.html"), []byte(`<a href="{{urlfor "user" "id" .}}">me</a>`), 0644)
+
+		e := New()
+		So(e.LoadHTMLTemplates(TemplateOptions{Directory: dir}), ShouldBeNil)
+		e.GET("/users/:id", showUser).Name("user")
+		e.GET("/link", func(ctx *Context) { ctx.Render("link", 7) })
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/link", nil)
+		e.ServeHTTP(w, req)
+		So(w.Body.String(), ShouldEqual, `<a href="/users/7">me</a>`)
+	})
+}
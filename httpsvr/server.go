@@ -1,13 +1,17 @@
 package httpsvr
 
 import (
+	"context"
+	"errors"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"runtime"
+	"sync"
+	"syscall"
 	"time"
 
-	"github.com/simplejia/utils"
-	"github.com/tylerb/graceful"
-
 	"github.com/hydah/golib/logger"
 )
 
@@ -25,17 +29,39 @@ type HTTPServer struct {
 	// maximum duration before timing out write of the response
 	WriteTimeout time.Duration
 
-	// enable hijact signal
+	// enable hijact signal, SIGUSR2 restarts the server, see Restart
 	enableHijactSignal bool
+
+	lock          sync.Mutex
+	servers       []*http.Server
+	listeners     []net.Listener
+	shutdownHooks []func()
+	serving       sync.WaitGroup
+	newConns      sync.Map
+	closing       bool
+	reloaders     []*CertReloader
+	signalOnce    sync.Once
+	reloadOnce    sync.Once
+	shutdownOnce  sync.Once
+	shutdownErr   error
+	done          chan struct{}
 }
 
 func NewHTTPServer() *HTTPServer {
+	return newHTTPServer(New())
+}
+
+// newHTTPServer returns an HTTPServer serving engine with the default
+// timeouts.
+func newHTTPServer(engine *Engine) *HTTPServer {
 	s := &HTTPServer{
-		engine: New(),
+		engine: engine,
 
 		DelayTimeout: 1 * time.Second,
 		ReadTimeout:  300 * time.Second,
 		WriteTimeout: 300 * time.Second,
+
+		done: make(chan struct{}),
 	}
 	return s
 }
@@ -44,6 +70,8 @@ func (s *HTTPServer) Init() {
 	runtime.GOMAXPROCS(runtime.NumCPU())
 }
 
+// EnableHijactSignal makes the server restart without dropping connections
+// on SIGUSR2, see Restart. It does nothing on windows.
 func (s *HTTPServer) EnableHijactSignal() {
 	s.enableHijactSignal = true
 }
@@ -69,61 +97,268 @@ func (s *HTTPServer) Put(pattern string, c IController) {
 }
 
 func (s *HTTPServer) AddRoute(method string, pattern string, c IController) {
-	switch method {
-	case "GET":
-		s.engine.GETController(pattern, c)
-	case "POST":
-		s.engine.POSTController(pattern, c)
-	default:
+	handler := controllerMethod(c, method)
+	if handler == nil {
 		logger.Error("method [%s] mismatch", method)
+		return
 	}
+	s.engine.HandleController(method, pattern, c, handler)
+}
+
+// AddRouteFunc is AddRoute with the controllers made by factory, see
+// RouterGroup.HandleControllerFunc.
+func (s *HTTPServer) AddRouteFunc(method string, pattern string, factory ControllerFactory) {
+	s.engine.HandleControllerFunc(method, pattern, factory)
+}
+
+// Resource maps the actions of a resource controller on pattern, see
+// RouterGroup.Resource.
+func (s *HTTPServer) Resource(pattern string, c IController) *RouterGroup {
+	return s.engine.Resource(pattern, c)
 }
 
 func (s *HTTPServer) Static(path, dir string) {
 	s.engine.Static(path, dir)
 }
 
+// ServeFile serves the file on path.
+func (s *HTTPServer) ServeFile(path, file string) {
+	s.engine.StaticFile(path, file)
+}
+
+// OnShutdown registers a function to call once the server is shut down and
+// the in-flight requests are drained. Hooks are called in the order they
+// were registered.
+func (s *HTTPServer) OnShutdown(fn func()) {
+	s.lock.Lock()
+	s.shutdownHooks = append(s.shutdownHooks, fn)
+	s.lock.Unlock()
+}
+
+// Run serves HTTP on hostport until the server is shut down, either by
+// Shutdown or by SIGTERM, SIGINT or SIGQUIT. It returns once the shutdown
+// is complete.
+// A listener inherited from a parent process or from systemd socket
+// activation is used when its address matches hostport.
 func (s *HTTPServer) Run(hostport string) error {
-	if s.enableHijactSignal {
-		waitTime := time.Second * 5
-		startTime := time.Second * 5
-		err := utils.ListenAndServeWithTimeout(
-			hostport,
-			s.engine,
-			waitTime,
-			startTime,
-		)
+	return s.RunListeners(ListenConfig{Addr: hostport})
+}
+
+// RunAsHttps serves HTTPS on hostport, see Run. The certificate is reloaded
+// on SIGHUP.
+func (s *HTTPServer) RunAsHttps(hostport, cert, key string) error {
+	return s.RunListeners(ListenConfig{Addr: hostport, TLS: &TLSOptions{CertFile: cert, KeyFile: key}})
+}
+
+// RunListeners serves on every listener at once until the server is shut
+// down, see Run. When one of them fails the others are shut down and the
+// error is returned.
+func (s *HTTPServer) RunListeners(listeners ...ListenConfig) error {
+	if len(listeners) == 0 {
+		return errors.New("httpsvr: no listener")
+	}
+	if AppEnv == DEV {
+		s.engine.printRoutes()
+	}
+	errs := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func(l ListenConfig) {
+			l = l.normalize()
+			err := s.serve(l)
+			if err != nil {
+				logger.Error("%v, host: %v", err, l)
+			}
+			errs <- err
+		}(l)
+	}
+	var first error
+	for range listeners {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+			go s.Shutdown(context.Background())
+		}
+	}
+	return first
+}
+
+// Shutdown gracefully shuts down every listener started by Run and
+// RunAsHttps together: they stop accepting connections, in-flight requests
+// are drained until ctx is done or DelayTimeout passes, then the remaining
+// connections are closed and the OnShutdown hooks are called.
+// Calling Shutdown more than once returns the result of the first call.
+func (s *HTTPServer) Shutdown(ctx context.Context) error {
+	s.shutdownOnce.Do(func() {
+		if s.DelayTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.DelayTimeout)
+			defer cancel()
+		}
+
+		s.lock.Lock()
+		s.closing = true
+		servers := s.servers
+		listeners := s.listeners
+		hooks := s.shutdownHooks
+		s.lock.Unlock()
+
+		// Stop accepting first and let the connections already accepted
+		// send their request, http.Server.Shutdown would drop them.
+		for _, ln := range listeners {
+			ln.Close()
+		}
+		s.serving.Wait()
+		s.waitNewConns(ctx)
+		s.shutdownErr = shutdownServers(ctx, servers)
+
+		for _, hook := range hooks {
+			hook()
+		}
+		close(s.done)
+	})
+	return s.shutdownErr
+}
+
+// shutdownServers shuts the servers down together, the ones which did not
+// drain before ctx is done are closed.
+func shutdownServers(ctx context.Context, servers []*http.Server) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(servers))
+	for i, srv := range servers {
+		wg.Add(1)
+		go func(i int, srv *http.Server) {
+			defer wg.Done()
+			if errs[i] = srv.Shutdown(ctx); errs[i] != nil {
+				srv.Close()
+			}
+		}(i, srv)
+	}
+	wg.Wait()
+	for _, err := range errs {
 		if err != nil {
-			logger.Error("%v, host: %v", err, utils.LocalIp)
 			return err
 		}
+	}
+	return nil
+}
+
+func (s *HTTPServer) newServer(l ListenConfig) *http.Server {
+	srv := &http.Server{
+		Addr:         l.Addr,
+		Handler:      s.engine,
+		ReadTimeout:  s.ReadTimeout,
+		WriteTimeout: s.WriteTimeout,
+		ConnState:    s.trackConnState,
+	}
+	if l.TLSConfig != nil {
+		srv.TLSConfig = l.TLSConfig.Clone()
+	}
+	return srv
+}
 
+func (s *HTTPServer) trackConnState(c net.Conn, state http.ConnState) {
+	if state == http.StateNew {
+		s.newConns.Store(c, struct{}{})
 	} else {
-		srv := &graceful.Server{
-			Server: &http.Server{Addr: hostport, Handler: s.engine},
+		s.newConns.Delete(c)
+	}
+}
+
+// waitNewConns waits until every accepted connection has sent its request
+// or ctx is done.
+func (s *HTTPServer) waitNewConns(ctx context.Context) {
+	for {
+		empty := true
+		s.newConns.Range(func(key, value interface{}) bool {
+			empty = false
+			return false
+		})
+		if empty {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(10 * time.Millisecond):
 		}
-		srv.Timeout = s.DelayTimeout
-		srv.Server.ReadTimeout = s.ReadTimeout
-		srv.Server.WriteTimeout = s.WriteTimeout
-		if err := srv.ListenAndServe(); err != nil {
-			logger.Error("%v, host: %v", err, utils.LocalIp)
+	}
+}
+
+// serve listens on l, tracks its server and listener so that Shutdown and
+// Restart know them, and waits for the shutdown to complete once serving
+// stops with http.ErrServerClosed.
+func (s *HTTPServer) serve(l ListenConfig) error {
+	srv := s.newServer(l)
+	var reloader *CertReloader
+	if l.TLS != nil {
+		cfg, r, err := l.TLS.Config()
+		if err != nil {
 			return err
 		}
+		srv.TLSConfig, reloader = cfg, r
+	}
+	s.lock.Lock()
+	if s.closing {
+		s.lock.Unlock()
+		return http.ErrServerClosed
+	}
+	ln, err := listen(l)
+	if err != nil {
+		s.lock.Unlock()
+		return err
 	}
+	ln = &onceCloseListener{Listener: ln}
+	s.servers = append(s.servers, srv)
+	s.listeners = append(s.listeners, ln)
+	s.serving.Add(1)
+	s.lock.Unlock()
 
-	return nil
-}
+	s.signalOnce.Do(s.handleSignals)
+	if reloader != nil {
+		s.addCertReloader(reloader, l.TLS.ReloadInterval)
+	}
+	notifyParent()
 
-func (s *HTTPServer) RunAsHttps(hostport, cert, key string) error {
-	srv := &graceful.Server{
-		Server: &http.Server{Addr: hostport, Handler: s.engine},
-	}
-	srv.Timeout = s.DelayTimeout
-	srv.Server.ReadTimeout = s.ReadTimeout
-	srv.Server.WriteTimeout = s.WriteTimeout
-	if err := srv.ListenAndServeTLS(cert, key); err != nil {
-		logger.Error("%v, host: %v", err, utils.LocalIp)
+	if l.isTLS() {
+		err = srv.ServeTLS(ln, "", "")
+	} else {
+		err = srv.Serve(ln)
+	}
+	s.serving.Done()
+	s.lock.Lock()
+	closing := s.closing
+	s.lock.Unlock()
+	if !closing && err != http.ErrServerClosed {
 		return err
 	}
+	<-s.done
 	return nil
 }
+
+func (s *HTTPServer) handleSignals() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
+	if s.enableHijactSignal && restartSignal != nil {
+		signal.Notify(ch, restartSignal)
+	}
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case sig := <-ch:
+				if sig == restartSignal {
+					if err := s.Restart(); err != nil {
+						logger.Error("[%s] restart: %v", s.engine.AppName, err)
+					}
+					continue
+				}
+				logger.Info("[%s] receive signal %v, shutting down", s.engine.AppName, sig)
+				if err := s.Shutdown(context.Background()); err != nil {
+					logger.Error("[%s] shutdown: %v", s.engine.AppName, err)
+				}
+				return
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,100 @@
+package httpsvr
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_HTTPServerShutdown(t *testing.T) {
+	Convey("Shutdown drains in-flight requests and calls hooks in order", t, func() {
+		s := NewHTTPServer()
+		s.DelayTimeout = 2 * time.Second
+		started := make(chan struct{})
+		s.engine.GET("/slow", func(ctx *Context) {
+			close(started)
+			time.Sleep(200 * time.Millisecond)
+			ctx.Text("done")
+		})
+		var hooks []int
+		s.OnShutdown(func() { hooks = append(hooks, 1) })
+		s.OnShutdown(func() { hooks = append(hooks, 2) })
+
+		addr := freeAddr()
+		runErr := make(chan error, 1)
+		go func() { runErr <- s.Run(addr) }()
+		waitListening(addr)
+
+		body := make(chan string, 1)
+		go func() {
+			resp, err := http.Get("http://" + addr + "/slow")
+			if err != nil {
+				body <- err.Error()
+				return
+			}
+			defer resp.Body.Close()
+			b, _ := ioutil.ReadAll(resp.Body)
+			body <- string(b)
+		}()
+		<-started
+
+		So(s.Shutdown(context.Background()), ShouldBeNil)
+		So(<-body, ShouldEqual, "done")
+		So(<-runErr, ShouldBeNil)
+		So(hooks, ShouldResemble, []int{1, 2})
+
+		_, err := http.Get("http://" + addr + "/slow")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Draining is bounded by DelayTimeout", t, func() {
+		s := NewHTTPServer()
+		s.DelayTimeout = 50 * time.Millisecond
+		started := make(chan struct{})
+		s.engine.GET("/hang", func(ctx *Context) {
+			close(started)
+			time.Sleep(time.Second)
+		})
+		addr := freeAddr()
+		go s.Run(addr)
+		waitListening(addr)
+		go http.Get("http://" + addr + "/hang")
+		<-started
+
+		begin := time.Now()
+		So(s.Shutdown(context.Background()) == context.DeadlineExceeded, ShouldBeTrue)
+		So(time.Since(begin), ShouldBeLessThan, 500*time.Millisecond)
+	})
+
+	Convey("The server of Engine.Run has the default timeouts", t, func() {
+		s := New().httpServer()
+		def := NewHTTPServer()
+		So(s.DelayTimeout, ShouldEqual, def.DelayTimeout)
+		So(s.ReadTimeout, ShouldEqual, def.ReadTimeout)
+		So(s.WriteTimeout, ShouldEqual, def.WriteTimeout)
+	})
+}
+
+func freeAddr() string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func waitListening(addr string) {
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,194 @@
+package httpsvr
+
+import (
+	"encoding/base32"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// defaultSessionMaxAge is used by server side stores when a session has no
+// MaxAge, so that browser-session cookies do not keep data forever.
+const defaultSessionMaxAge = 86400 * 30
+
+// CookieStore keeps the whole session in a cookie which is signed with the
+// first key of each pair and, when the second key is given, encrypted with it.
+type CookieStore interface {
+	SessionStore
+}
+
+// NewCookieStore returns a CookieStore.
+// Keys are defined in pairs to allow key rotation, see sessions.NewCookieStore.
+func NewCookieStore(keyPairs ...[]byte) CookieStore {
+	return &cookieStore{sessions.NewCookieStore(keyPairs...)}
+}
+
+type cookieStore struct {
+	*sessions.CookieStore
+}
+
+func (c *cookieStore) Options(options SessionOptions) {
+	c.CookieStore.Options = options.toGorilla()
+}
+
+// FilesystemStore keeps the session values in files under a directory, the
+// cookie only holds the signed session id.
+type FilesystemStore interface {
+	SessionStore
+}
+
+// NewFilesystemStore returns a FilesystemStore storing files in path,
+// os.TempDir() is used if path is empty.
+func NewFilesystemStore(path string, keyPairs ...[]byte) FilesystemStore {
+	return &filesystemStore{sessions.NewFilesystemStore(path, keyPairs...)}
+}
+
+type filesystemStore struct {
+	*sessions.FilesystemStore
+}
+
+func (c *filesystemStore) Options(options SessionOptions) {
+	c.FilesystemStore.Options = options.toGorilla()
+}
+
+// MemoryStore keeps the session values in process memory, the cookie only
+// holds the signed session id. Sessions expire after their MaxAge.
+type MemoryStore interface {
+	SessionStore
+	// Len returns the number of sessions kept in memory.
+	Len() int
+}
+
+// NewMemoryStore returns a MemoryStore, keyPairs are used to sign and
+// optionally encrypt the session id cookie.
+func NewMemoryStore(keyPairs ...[]byte) MemoryStore {
+	return &memoryStore{
+		codecs: securecookie.CodecsFromPairs(keyPairs...),
+		options: &sessions.Options{
+			Path:   "/",
+			MaxAge: defaultSessionMaxAge,
+		},
+		items: make(map[string]*memoryItem),
+	}
+}
+
+type memoryStore struct {
+	codecs  []securecookie.Codec
+	options *sessions.Options
+	lock    sync.Mutex
+	items   map[string]*memoryItem
+	lastGC  time.Time
+}
+
+type memoryItem struct {
+	values  map[interface{}]interface{}
+	expires time.Time
+}
+
+func (c *memoryStore) Options(options SessionOptions) {
+	c.lock.Lock()
+	c.options = options.toGorilla()
+	c.lock.Unlock()
+}
+
+func (c *memoryStore) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.items)
+}
+
+// Get returns a session for the given name after adding it to the registry.
+func (c *memoryStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(c, name)
+}
+
+// New returns the session for the given name, a fresh one if the cookie is
+// missing, invalid or the session has expired.
+func (c *memoryStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(c, name)
+	c.lock.Lock()
+	opts := *c.options
+	c.lock.Unlock()
+	session.Options = &opts
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	if err = securecookie.DecodeMulti(name, cookie.Value, &session.ID, c.codecs...); err != nil {
+		session.ID = ""
+		return session, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	item, ok := c.items[session.ID]
+	if !ok || time.Now().After(item.expires) {
+		delete(c.items, session.ID)
+		return session, nil
+	}
+	for k, v := range item.values {
+		session.Values[k] = v
+	}
+	session.IsNew = false
+	return session, nil
+}
+
+// Save stores the session values and writes the session id cookie. A session
+// with MaxAge < 0 is removed from memory and its cookie is expired.
+func (c *memoryStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		c.lock.Lock()
+		delete(c.items, session.ID)
+		c.lock.Unlock()
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		session.ID = strings.TrimRight(
+			base32.StdEncoding.EncodeToString(securecookie.GenerateRandomKey(32)), "=")
+	}
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, c.codecs...)
+	if err != nil {
+		return err
+	}
+
+	maxAge := session.Options.MaxAge
+	if maxAge == 0 {
+		maxAge = defaultSessionMaxAge
+	}
+	item := &memoryItem{
+		values:  make(map[interface{}]interface{}, len(session.Values)),
+		expires: time.Now().Add(time.Duration(maxAge) * time.Second),
+	}
+	for k, v := range session.Values {
+		item.values[k] = v
+	}
+
+	c.lock.Lock()
+	c.items[session.ID] = item
+	c.gc(time.Now())
+	c.lock.Unlock()
+
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// gc removes expired sessions, at most once a minute. Must hold c.lock.
+func (c *memoryStore) gc(now time.Time) {
+	if now.Sub(c.lastGC) < time.Minute {
+		return
+	}
+	c.lastGC = now
+	for id, item := range c.items {
+		if now.After(item.expires) {
+			delete(c.items, id)
+		}
+	}
+}
@@ -1,7 +1,11 @@
 package httpsvr
 
 import (
+	"net/http"
+
 	"github.com/gorilla/sessions"
+
+	"github.com/hydah/golib/logger"
 )
 
 // SessionStore is an interface for custom session stores.
@@ -28,4 +32,110 @@ type Session interface {
 	AddFlash(value interface{}, vars ...string)
 	Flashes(vars ...string) []interface{}
 	Options(SessionOptions)
+	// Save writes the session to the response. It is called automatically
+	// before the response headers are sent if the session was modified.
+	Save() error
+}
+
+// Sessions returns a middleware that loads the session named name from store
+// into Context.Session before the handler chain runs, and saves it again
+// before the response headers are written.
+func Sessions(name string, store SessionStore) HandlerFunc {
+	return func(ctx *Context) {
+		s := &session{name: name, req: ctx.Req, store: store, writer: ctx.Writer}
+		ctx.Session = s
+		ctx.Writer.Before(func(ResponseWriter) {
+			if !s.written {
+				return
+			}
+			if err := s.Save(); err != nil {
+				logger.Error("[%s] save session %s: %v", ctx.Engine.AppName, name, err)
+			}
+		})
+		ctx.Next()
+	}
+}
+
+type session struct {
+	name    string
+	req     *http.Request
+	store   SessionStore
+	session *sessions.Session
+	written bool
+	writer  http.ResponseWriter
+}
+
+func (s *session) Get(key interface{}) interface{} {
+	return s.Session().Values[key]
+}
+
+func (s *session) Set(key interface{}, val interface{}) {
+	s.Session().Values[key] = val
+	s.written = true
+}
+
+func (s *session) Delete(key interface{}) {
+	delete(s.Session().Values, key)
+	s.written = true
+}
+
+func (s *session) Clear() {
+	for key := range s.Session().Values {
+		s.Delete(key)
+	}
+}
+
+func (s *session) AddFlash(value interface{}, vars ...string) {
+	s.Session().AddFlash(value, vars...)
+	s.written = true
+}
+
+func (s *session) Flashes(vars ...string) []interface{} {
+	flashes := s.Session().Flashes(vars...)
+	if len(flashes) > 0 {
+		s.written = true
+	}
+	return flashes
+}
+
+func (s *session) Options(options SessionOptions) {
+	s.Session().Options = options.toGorilla()
+	s.written = true
+}
+
+func (s *session) Save() error {
+	if s.session == nil {
+		return nil
+	}
+	err := s.session.Save(s.req, s.writer)
+	if err == nil {
+		s.written = false
+	}
+	return err
+}
+
+// Session lazily loads the underlying gorilla session. Store.New is used
+// instead of Store.Get so that nothing is left behind in the request registry.
+func (s *session) Session() *sessions.Session {
+	if s.session == nil {
+		var err error
+		s.session, err = s.store.New(s.req, s.name)
+		if err != nil {
+			logger.Warn("load session %s: %v", s.name, err)
+		}
+		if s.session == nil {
+			s.session = sessions.NewSession(s.store, s.name)
+		}
+	}
+	return s.session
+}
+
+func (o SessionOptions) toGorilla() *sessions.Options {
+	return &sessions.Options{
+		Path:     o.Path,
+		Domain:   o.Domain,
+		MaxAge:   o.MaxAge,
+		Secure:   o.Secure,
+		HttpOnly: o.HTTPOnly,
+	}
 }
@@ -0,0 +1,74 @@
+package httpsvr
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_Sessions(t *testing.T) {
+	Convey("Cookie store", t, func() {
+		testSessionStore(NewCookieStore([]byte("secret-hash-key"), []byte("0123456789abcdef")))
+	})
+	Convey("Memory store", t, func() {
+		store := NewMemoryStore([]byte("secret-hash-key"))
+		testSessionStore(store)
+		// the session was removed by /clear
+		So(store.Len(), ShouldEqual, 0)
+	})
+	Convey("Filesystem store", t, func() {
+		dir, err := ioutil.TempDir("", "httpsvr-sessions")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		testSessionStore(NewFilesystemStore(dir, []byte("secret-hash-key")))
+	})
+}
+
+func testSessionStore(store SessionStore) {
+	m := New()
+	m.Use(Sessions("sid", store))
+	m.GET("/set", func(ctx *Context) {
+		ctx.Session.Set("user", "http")
+		ctx.Session.AddFlash("hello")
+		ctx.Text("ok")
+	})
+	m.GET("/get", func(ctx *Context) {
+		user, _ := ctx.Session.Get("user").(string)
+		flashes := ctx.Session.Flashes()
+		ctx.Json(JSON{"user": user, "flashes": len(flashes)})
+	})
+	m.GET("/clear", func(ctx *Context) {
+		ctx.Session.Clear()
+		ctx.Session.Options(SessionOptions{Path: "/", MaxAge: -1})
+	})
+
+	w := performRequest(m, "GET", "/set")
+	cookie := w.Header().Get("Set-Cookie")
+	So(cookie, ShouldStartWith, "sid=")
+
+	w = sessionRequest(m, "/get", cookie)
+	So(w.Body.String(), ShouldEqual, `{"flashes":1,"user":"http"}`)
+	// flashes are consumed by the first read
+	w = sessionRequest(m, "/get", w.Header().Get("Set-Cookie"))
+	So(w.Body.String(), ShouldEqual, `{"flashes":0,"user":"http"}`)
+	// no modification, no cookie
+	So(w.Header().Get("Set-Cookie"), ShouldBeEmpty)
+
+	w = sessionRequest(m, "/get", "sid=tampered")
+	So(w.Body.String(), ShouldEqual, `{"flashes":0,"user":""}`)
+
+	w = sessionRequest(m, "/clear", cookie)
+	So(w.Header().Get("Set-Cookie"), ShouldContainSubstring, "Max-Age=0")
+}
+
+func sessionRequest(m *Engine, path, cookie string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Header.Set("Cookie", cookie)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	return w
+}
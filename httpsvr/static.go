@@ -0,0 +1,189 @@
+package httpsvr
+
+import (
+	"html"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// StaticOptions configures the static file handlers.
+type StaticOptions struct {
+	// IndexFiles are served for a directory, default "index.html".
+	IndexFiles []string
+	// Browse lists the directories without an index file, instead of a 404.
+	Browse bool
+	// Fallback is served instead of a 404 for missing files, e.g.
+	// "/index.html" for a single page application.
+	Fallback string
+	// CacheControl returns the Cache-Control header of a file, e.g.
+	//
+	//	func(name string) string {
+	//		if strings.HasSuffix(name, ".html") {
+	//			return "no-cache"
+	//		}
+	//		return "public, max-age=31536000, immutable"
+	//	}
+	CacheControl func(name string) string
+	// Precompressed serves name.gz, when it exists, to the clients accepting
+	// gzip.
+	Precompressed bool
+}
+
+// StaticHandler returns a handler serving the files of fsys, the file is the
+// *filepath parameter of the route, or the request path without one, e.g.
+// when used as NoRoute handler. Ranges and If-Modified-Since are honored.
+func StaticHandler(fsys http.FileSystem, opts ...StaticOptions) HandlerFunc {
+	var o StaticOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.IndexFiles == nil {
+		o.IndexFiles = []string{"index.html"}
+	}
+	return func(ctx *Context) {
+		if ctx.Req.Method != "GET" && ctx.Req.Method != "HEAD" {
+			return
+		}
+		name := ctx.Params.ByName("filepath")
+		if name == "" {
+			name = ctx.Req.URL.Path
+		}
+		name = path.Clean("/" + name)
+		if serveStatic(ctx, fsys, name, &o) || o.Fallback != "" && serveStatic(ctx, fsys, path.Clean("/"+o.Fallback), &o) {
+			ctx.Abort()
+			return
+		}
+		// as NoRoute handler the next handlers answer
+		if ctx.route != "" {
+			ctx.Engine.notFound(ctx)
+			ctx.Abort()
+		}
+	}
+}
+
+// serveStatic serves name, it is false if there is nothing to serve.
+func serveStatic(ctx *Context, fsys http.FileSystem, name string, o *StaticOptions) bool {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	if !info.IsDir() {
+		serveStaticFile(ctx, fsys, name, f, info, o)
+		return true
+	}
+
+	// directories are served at a path ending in a slash, as relative links
+	// in their index expect
+	if urlPath := ctx.Req.URL.Path; !strings.HasSuffix(urlPath, "/") {
+		target := path.Base(urlPath) + "/"
+		if ctx.Req.URL.RawQuery != "" {
+			target += "?" + ctx.Req.URL.RawQuery
+		}
+		ctx.Redirect(target, http.StatusMovedPermanently)
+		return true
+	}
+	for _, index := range o.IndexFiles {
+		indexName := path.Join(name, index)
+		ff, err := fsys.Open(indexName)
+		if err != nil {
+			continue
+		}
+		defer ff.Close()
+		if info, err := ff.Stat(); err == nil && !info.IsDir() {
+			serveStaticFile(ctx, fsys, indexName, ff, info, o)
+			return true
+		}
+	}
+	if !o.Browse {
+		return false
+	}
+	return listDir(ctx, f)
+}
+
+func serveStaticFile(ctx *Context, fsys http.FileSystem, name string, f http.File, info os.FileInfo, o *StaticOptions) {
+	header := ctx.Writer.Header()
+	if o.CacheControl != nil {
+		if cc := o.CacheControl(name); cc != "" {
+			header.Set(HeaderCacheControl, cc)
+		}
+	}
+	if o.Precompressed {
+		header.Add(HeaderVary, HeaderAcceptEncoding)
+		if negotiateEncoding(ctx.Req.Header.Get(HeaderAcceptEncoding), []string{EncodingGzip}) != "" {
+			if gz, err := fsys.Open(name + ".gz"); err == nil {
+				defer gz.Close()
+				if gzInfo, err := gz.Stat(); err == nil && !gzInfo.IsDir() {
+					contentType := mime.TypeByExtension(path.Ext(name))
+					if contentType == "" {
+						contentType = "application/octet-stream"
+					}
+					header.Set(HeaderContentType, contentType)
+					header.Set(HeaderContentEncoding, EncodingGzip)
+					http.ServeContent(ctx.Writer, ctx.Req, name, gzInfo.ModTime(), gz)
+					return
+				}
+			}
+		}
+	}
+	http.ServeContent(ctx.Writer, ctx.Req, name, info.ModTime(), f)
+}
+
+// listDir writes an HTML listing of the directory.
+func listDir(ctx *Context, dir http.File) bool {
+	entries, err := dir.Readdir(-1)
+	if err != nil {
+		return false
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	var b strings.Builder
+	b.WriteString("<pre>\n")
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		link := url.URL{Path: name}
+		b.WriteString(`<a href="` + html.EscapeString(link.String()) + `">` + html.EscapeString(name) + "</a>\n")
+	}
+	b.WriteString("</pre>\n")
+	ctx.Html(b.String(), http.StatusOK)
+	return true
+}
+
+// StaticFS serves the files of fsys under relativePath through the
+// middlewares of the group, missing files get the NoRoute handlers of the
+// path.
+func (c *RouterGroup) StaticFS(relativePath string, fsys http.FileSystem, opts ...StaticOptions) {
+	if lastChar(relativePath) != '/' {
+		relativePath += "/"
+	}
+	relativePath += "*filepath"
+	handler := StaticHandler(fsys, opts...)
+	c.GET(relativePath, handler)
+	c.HEAD(relativePath, handler)
+}
+
+// StaticIOFS is StaticFS for an fs.FS, e.g. an embed.FS.
+func (c *RouterGroup) StaticIOFS(relativePath string, fsys fs.FS, opts ...StaticOptions) {
+	c.StaticFS(relativePath, http.FS(fsys), opts...)
+}
+
+// StaticFile serves the file on relativePath.
+func (c *RouterGroup) StaticFile(relativePath, file string) {
+	handler := func(ctx *Context) {
+		http.ServeFile(ctx.Writer, ctx.Req, file)
+	}
+	c.GET(relativePath, handler)
+	c.HEAD(relativePath, handler)
+}
@@ -0,0 +1,79 @@
+package httpsvr
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_StaticFS(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("console.log('zipped')"))
+	zw.Close()
+	fsys := fstest.MapFS{
+		"index.html":       {Data: []byte("<h1>home</h1>")},
+		"app.js":           {Data: []byte("console.log('plain')")},
+		"app.js.gz":        {Data: gz.Bytes()},
+		"docs/readme.txt":  {Data: []byte("readme")},
+		"docs/guide/a.txt": {Data: []byte("a")},
+	}
+
+	Convey("Static files run through the group middlewares", t, func() {
+		var seen []string
+		e := New()
+		e.Group("/assets", func(g *RouterGroup) {
+			g.Use(func(ctx *Context) { seen = append(seen, ctx.Req.URL.Path) })
+			g.StaticIOFS("/", fsys, StaticOptions{
+				Browse:        true,
+				Precompressed: true,
+				CacheControl:  func(name string) string { return "max-age=60" },
+			})
+		})
+
+		w := performRequest(e, "GET", "/assets/")
+		So(w.Body.String(), ShouldEqual, "<h1>home</h1>")
+		So(seen, ShouldResemble, []string{"/assets/"})
+
+		w = performRequest(e, "GET", "/assets/app.js")
+		So(w.Body.String(), ShouldEqual, "console.log('plain')")
+		So(w.Header().Get(HeaderCacheControl), ShouldEqual, "max-age=60")
+		So(w.Header().Get(HeaderVary), ShouldEqual, HeaderAcceptEncoding)
+
+		w = performRequestWith(e, "GET", "/assets/app.js", http.Header{HeaderAcceptEncoding: {"gzip"}}, nil)
+		So(w.Header().Get(HeaderContentEncoding), ShouldEqual, EncodingGzip)
+		So(w.Header().Get(HeaderContentType), ShouldStartWith, "text/javascript")
+		So(w.Body.Bytes(), ShouldResemble, gz.Bytes())
+
+		w = performRequest(e, "GET", "/assets/docs")
+		So(w.Code, ShouldEqual, http.StatusMovedPermanently)
+		So(w.Header().Get("Location"), ShouldEqual, "/assets/docs/")
+		w = performRequest(e, "GET", "/assets/docs/")
+		So(w.Body.String(), ShouldContainSubstring, `<a href="guide/">guide/</a>`)
+		So(w.Body.String(), ShouldContainSubstring, `<a href="readme.txt">readme.txt</a>`)
+
+		w = performRequest(e, "GET", "/assets/missing.js")
+		So(w.Code, ShouldEqual, http.StatusNotFound)
+		So(w.Body.String(), ShouldEqual, notFoundPage)
+		So(seen, ShouldContain, "/assets/missing.js")
+	})
+
+	Convey("Static falls back to the index of a single page application", t, func() {
+		e := New()
+		e.StaticIOFS("/app", fsys, StaticOptions{Fallback: "index.html"})
+		w := performRequest(e, "GET", "/app/users/42")
+		So(w.Code, ShouldEqual, 200)
+		So(w.Body.String(), ShouldEqual, "<h1>home</h1>")
+		So(performRequest(e, "GET", "/app/docs/").Body.String(), ShouldEqual, "<h1>home</h1>")
+	})
+
+	Convey("StaticFile serves one file", t, func() {
+		e := New()
+		e.StaticFile("/style.css", "test/test.css")
+		So(performRequest(e, "GET", "/style.css").Code, ShouldEqual, 200)
+	})
+}
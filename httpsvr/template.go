@@ -0,0 +1,251 @@
+package httpsvr
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hydah/golib/httpsvr/render"
+)
+
+// TemplateOptions configures Templates.
+type TemplateOptions struct {
+	// Directory is the root of the templates, default "templates".
+	Directory string
+	// Extensions of the template files, default [".tmpl", ".html"].
+	Extensions []string
+	// Layouts and Partials are the sub directories holding the templates
+	// shared by every view, default "layouts" and "partials".
+	Layouts  string
+	Partials string
+	// Layout is the default layout every view is rendered in, e.g.
+	// "layouts/main". Empty renders views on their own.
+	Layout string
+	// Funcs are added to every template.
+	Funcs []template.FuncMap
+	// Delims are the action delimiters, default "{{" and "}}".
+	Delims []string
+}
+
+// Templates is a html/template based HtmlEngine backend.
+//
+// Templates are named by their path relative to Directory without the
+// extension, e.g. "users/index". Each view is parsed together with all the
+// layouts and partials, so a view can {{template "partials/header" .}} and
+// {{define}} the blocks its layout declares with {{block}}. A layout can also
+// insert the whole view with {{yield .}}.
+//
+// Views are parsed once and cached, except when AppEnv is DEV where they are
+// parsed again on every render.
+type Templates struct {
+	opts  TemplateOptions
+	lock  sync.RWMutex
+	views map[string]*template.Template
+}
+
+var errNoTemplates = errors.New("html templates are not loaded, see Engine.LoadHTMLTemplates")
+
+// NewTemplates loads the templates described by opts.
+// Outside DEV every view is parsed at once so that errors show up at start.
+func NewTemplates(opts TemplateOptions) (*Templates, error) {
+	if opts.Directory == "" {
+		opts.Directory = "templates"
+	}
+	if len(opts.Extensions) == 0 {
+		opts.Extensions = []string{".tmpl", ".html"}
+	}
+	if opts.Layouts == "" {
+		opts.Layouts = "layouts"
+	}
+	if opts.Partials == "" {
+		opts.Partials = "partials"
+	}
+	if len(opts.Delims) != 2 {
+		opts.Delims = []string{"{{", "}}"}
+	}
+	t := &Templates{opts: opts, views: make(map[string]*template.Template)}
+	if AppEnv == DEV {
+		return t, nil
+	}
+	if err := t.compileAll(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Execute writes view rendered with data to w, inside the layout if given,
+// else inside the default layout.
+func (t *Templates) Execute(w io.Writer, view string, data interface{}, layout ...string) error {
+	tpl, err := t.lookup(view)
+	if err != nil {
+		return err
+	}
+	name := t.opts.Layout
+	if len(layout) > 0 {
+		name = layout[0]
+	}
+	if name == "" {
+		name = view
+	}
+	return tpl.ExecuteTemplate(w, name, data)
+}
+
+func (t *Templates) lookup(view string) (*template.Template, error) {
+	if AppEnv == DEV {
+		shared, files, err := t.scan()
+		if err != nil {
+			return nil, err
+		}
+		file, ok := files[view]
+		if !ok {
+			return nil, errors.New("html template " + view + " not found")
+		}
+		return t.compile(view, file, shared)
+	}
+
+	t.lock.RLock()
+	tpl, ok := t.views[view]
+	t.lock.RUnlock()
+	if !ok {
+		return nil, errors.New("html template " + view + " not found")
+	}
+	return tpl, nil
+}
+
+func (t *Templates) compileAll() error {
+	shared, files, err := t.scan()
+	if err != nil {
+		return err
+	}
+	views := make(map[string]*template.Template, len(files))
+	for name, file := range files {
+		if views[name], err = t.compile(name, file, shared); err != nil {
+			return err
+		}
+	}
+	t.lock.Lock()
+	t.views = views
+	t.lock.Unlock()
+	return nil
+}
+
+// scan walks the template directory and returns the shared templates and the
+// views, both mapping template names to file paths.
+func (t *Templates) scan() (shared, views map[string]string, err error) {
+	shared = make(map[string]string)
+	views = make(map[string]string)
+	err = filepath.Walk(t.opts.Directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !t.hasExtension(path) {
+			return nil
+		}
+		rel, err := filepath.Rel(t.opts.Directory, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		name := strings.TrimSuffix(rel, filepath.Ext(rel))
+		if strings.HasPrefix(rel, t.opts.Layouts+"/") || strings.HasPrefix(rel, t.opts.Partials+"/") {
+			shared[name] = path
+		} else {
+			views[name] = path
+		}
+		return nil
+	})
+	return
+}
+
+func (t *Templates) hasExtension(path string) bool {
+	ext := filepath.Ext(path)
+	for _, e := range t.opts.Extensions {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// compile parses the shared templates and then the view, so that the
+// {{define}}s of the view override the {{block}}s of the layouts.
+func (t *Templates) compile(view, file string, shared map[string]string) (*template.Template, error) {
+	tpl := template.New(view).Delims(t.opts.Delims[0], t.opts.Delims[1])
+	tpl.Funcs(template.FuncMap{
+		"yield": func(data interface{}) (template.HTML, error) {
+			var buf bytes.Buffer
+			err := tpl.ExecuteTemplate(&buf, view, data)
+			return template.HTML(buf.String()), err
+		},
+	})
+	for _, funcs := range t.opts.Funcs {
+		tpl.Funcs(funcs)
+	}
+	for name, path := range shared {
+		if err := parseFile(tpl.New(name), path); err != nil {
+			return nil, err
+		}
+	}
+	if err := parseFile(tpl, file); err != nil {
+		return nil, err
+	}
+	return tpl, nil
+}
+
+func parseFile(tpl *template.Template, path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	_, err = tpl.Parse(string(content))
+	return err
+}
+
+// LoadHTMLTemplates loads the templates used by Context.Render, they can
+// call {{urlfor "name" "param" value}}, see URLFor.
+func (c *Engine) LoadHTMLTemplates(opts TemplateOptions) error {
+	opts.Funcs = append([]template.FuncMap{{"urlfor": c.URLFor}}, opts.Funcs...)
+	t, err := NewTemplates(opts)
+	if err != nil {
+		return err
+	}
+	c.templates = t
+	return nil
+}
+
+// SetHTMLTemplates sets the templates used by Context.Render.
+func (c *Engine) SetHTMLTemplates(t *Templates) {
+	c.templates = t
+}
+
+// contextHtml is the default HtmlEngine of a Context, it renders the
+// templates of the Engine.
+type contextHtml struct {
+	ctx *Context
+}
+
+// Render renders view with data into the response, a failed render does not
+// write a partial page but a 500.
+func (h contextHtml) Render(view string, data interface{}, status ...int) error {
+	c := h.ctx
+	if c.Engine.templates == nil {
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		c.Abort()
+		return errNoTemplates
+	}
+	var buf bytes.Buffer
+	if err := c.Engine.templates.Execute(&buf, view, data); err != nil {
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		c.Abort()
+		return err
+	}
+	c.executeRender(buf.String(), c.Writer, render.HTML{}, status...)
+	return nil
+}
@@ -0,0 +1,59 @@
+package httpsvr
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/hydah/golib/httpsvr/render"
+)
+
+func Test_Templates(t *testing.T) {
+	for _, env := range []string{DEV, PROD} {
+		testTemplates(t, env)
+	}
+}
+
+func testTemplates(t *testing.T, env string) {
+	Convey("Render templates in "+env, t, func() {
+		defer func(old string) { AppEnv = old }(AppEnv)
+		AppEnv = env
+
+		m := New()
+		err := m.LoadHTMLTemplates(TemplateOptions{
+			Directory: "test/templates",
+			Layout:    "layouts/main",
+			Funcs:     []template.FuncMap{{"upper": strings.ToUpper}},
+		})
+		So(err, ShouldBeNil)
+		m.GET("/users", func(ctx *Context) {
+			ctx.Render("users/show", JSON{"Site": "golib", "Name": "<http>"}, http.StatusCreated)
+		})
+		m.GET("/missing", func(ctx *Context) {
+			So(ctx.Render("users/missing", nil), ShouldNotBeNil)
+		})
+
+		w := performRequest(m, "GET", "/users")
+		So(w.Code, ShouldEqual, http.StatusCreated)
+		So(w.Header().Get(render.ContentType), ShouldEqual, render.ContentHTML)
+		So(w.Body.String(), ShouldEqual, "<html><head><title>&lt;http&gt;</title></head><body><h1>golib</h1>\n"+
+			"<p>&lt;HTTP&gt;</p>\n</body></html>\n")
+
+		w = performRequest(m, "GET", "/missing")
+		So(w.Code, ShouldEqual, http.StatusInternalServerError)
+	})
+
+	Convey("Render without templates answers 500 in "+env, t, func() {
+		var err error
+		m := New()
+		m.GET("/users", func(ctx *Context) {
+			err = ctx.Render("users/show", nil)
+		})
+		w := performRequest(m, "GET", "/users")
+		So(err, ShouldEqual, errNoTemplates)
+		So(w.Code, ShouldEqual, http.StatusInternalServerError)
+	})
+}
@@ -0,0 +1,270 @@
+package httpsvr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hydah/golib/logger"
+)
+
+// ErrHandlerTimeout is returned by the writes of a handler that overran its
+// Timeout.
+var ErrHandlerTimeout = errors.New("httpsvr: handler timeout")
+
+// ErrHijackTimeout is returned by the hijacks of the handlers running under
+// Timeout, e.g. UpgradeWebSocket.
+var ErrHijackTimeout = errors.New("httpsvr: connection can not be hijacked under Timeout")
+
+// TimeoutOptions configures the response of Timeout.
+type TimeoutOptions struct {
+	// Status is 503 by default, 504 suits a gateway.
+	Status int
+	// Body is the text body, default the status text.
+	Body string
+}
+
+// Timeout returns a middleware giving the rest of the chain d to respond.
+// The request context gets the deadline, so that handlers and the calls they
+// make can give up, and the response is buffered until the chain returns.
+// The chain runs in another goroutine, Context.Logger keeps tagging its
+// records with the ids the request context carries.
+// When d passes first a 503 is written instead and the writes of the still
+// running handlers fail with ErrHandlerTimeout.
+// Responses can not be streamed under Timeout, and the hijacks fail with
+// ErrHijackTimeout.
+func Timeout(d time.Duration, opts ...TimeoutOptions) HandlerFunc {
+	var o TimeoutOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Status == 0 {
+		o.Status = http.StatusServiceUnavailable
+	}
+	if o.Body == "" {
+		o.Body = http.StatusText(o.Status)
+	}
+
+	return func(ctx *Context) {
+		reqCtx, cancel := context.WithTimeout(ctx.Req.Context(), d)
+		defer cancel()
+
+		orig := ctx.Writer
+		tw := &timeoutWriter{
+			ctx:    reqCtx,
+			orig:   orig,
+			header: cloneHeader(orig.Header()),
+			status: orig.Status(),
+			size:   noWritten,
+		}
+		// The rest of the chain runs on a copy so that it never shares
+		// state with this goroutine once it timed out.
+		cp := ctx.copyForTimeout(tw, ctx.Req.WithContext(reqCtx))
+		ctx.Writer = tw
+
+		done := make(chan struct{})
+		panicked := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				if err := recover(); err != nil {
+					panicked <- err
+					return
+				}
+				close(done)
+			}()
+			cp.Next()
+		}()
+
+		select {
+		case err := <-panicked:
+			ctx.Writer = orig
+			panic(err)
+		case <-done:
+			ctx.Writer = orig
+			if !tw.flush(o.Status, o.Body) {
+				ctx.Abort()
+				return
+			}
+			ctx.Keys = cp.Keys
+			ctx.Session = cp.Session
+			ctx.index = cp.index
+		case <-reqCtx.Done():
+			tw.lock.Lock()
+			tw.timedOut = true
+			tw.writeTimeout(o.Status, o.Body)
+			tw.lock.Unlock()
+			// the handlers may still use the context, keep it out of the pool
+			ctx.detached = true
+			ctx.Abort()
+			go func() {
+				select {
+				case <-done:
+				case err := <-panicked:
+					logger.Error("[%s] PANIC after timeout: %v", ctx.Engine.AppName, err)
+				}
+			}()
+		}
+	}
+}
+
+// copyForTimeout returns a copy of c running the rest of the chain with w
+// and req.
+func (c *Context) copyForTimeout(w ResponseWriter, req *http.Request) *Context {
+	cp := *c
+	cp.Writer = w
+	cp.Req = req
+	cp.underTimeout = true
+	if c.Keys != nil {
+		cp.Keys = make(map[string]interface{}, len(c.Keys))
+		for k, v := range c.Keys {
+			cp.Keys[k] = v
+		}
+	}
+	if _, ok := c.HtmlEngine.(contextHtml); ok {
+		cp.HtmlEngine = contextHtml{&cp}
+	}
+	return &cp
+}
+
+func cloneHeader(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for k, v := range h {
+		out[k] = append([]string(nil), v...)
+	}
+	return out
+}
+
+// timeoutWriter buffers the response of the handlers running under Timeout,
+// and their Before hooks so that they never run on the timeout response.
+type timeoutWriter struct {
+	lock        sync.Mutex
+	ctx         context.Context
+	orig        ResponseWriter
+	header      http.Header
+	buf         bytes.Buffer
+	status      int
+	size        int
+	beforeFuncs []beforeFunc
+	timedOut    bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.expired() {
+		return 0, ErrHandlerTimeout
+	}
+	if w.size == noWritten {
+		w.size = 0
+	}
+	n, err := w.buf.Write(data)
+	w.size += n
+	return n, err
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if code > 0 && !w.expired() {
+		w.status = code
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if !w.expired() && w.size == noWritten {
+		w.size = 0
+	}
+}
+
+// Flush does nothing, the response is written once the handlers return.
+func (w *timeoutWriter) Flush() {}
+
+// Hijack fails, Timeout may still write its response on the connection.
+func (w *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, ErrHijackTimeout
+}
+
+func (w *timeoutWriter) Status() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.timedOut {
+		return w.orig.Status()
+	}
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.timedOut {
+		return w.orig.Size()
+	}
+	return w.size
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.timedOut || w.size != noWritten
+}
+
+func (w *timeoutWriter) Before(fn func(ResponseWriter)) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if !w.expired() {
+		w.beforeFuncs = append(w.beforeFuncs, fn)
+	}
+}
+
+// expired reports whether the handlers can not write anymore, it is called
+// with the lock held.
+func (w *timeoutWriter) expired() bool {
+	if !w.timedOut && w.ctx.Err() != nil {
+		w.timedOut = true
+	}
+	return w.timedOut
+}
+
+// flush writes the buffered response once the handlers returned in time, or
+// the timeout response otherwise, it reports whether they were in time.
+func (w *timeoutWriter) flush(status int, body string) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.expired() {
+		w.writeTimeout(status, body)
+		return false
+	}
+	h := w.orig.Header()
+	for k := range h {
+		delete(h, k)
+	}
+	for k, v := range w.header {
+		h[k] = v
+	}
+	for _, fn := range w.beforeFuncs {
+		w.orig.Before(fn)
+	}
+	w.orig.WriteHeader(w.status)
+	if w.size != noWritten {
+		w.orig.WriteHeaderNow()
+		w.orig.Write(w.buf.Bytes())
+	}
+	return true
+}
+
+func (w *timeoutWriter) writeTimeout(status int, body string) {
+	w.orig.Header().Set(HeaderContentType, "text/plain; charset=utf-8")
+	w.orig.WriteHeader(status)
+	w.orig.Write([]byte(body))
+}
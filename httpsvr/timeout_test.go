@@ -0,0 +1,102 @@
+package httpsvr
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hydah/golib/httpsvr/trace"
+	"github.com/hydah/golib/logger"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_Timeout(t *testing.T) {
+	Convey("Timeout answers 503 and drops the late writes", t, func() {
+		late := make(chan error, 1)
+		cancelled := make(chan error, 1)
+		e := New()
+		e.Group("/api", func(api *RouterGroup) {
+			api.Use(Timeout(50 * time.Millisecond))
+			api.GET("/fast", func(ctx *Context) {
+				ctx.Writer.Header().Set("X-Fast", "1")
+				ctx.Set("user", "bob")
+				ctx.Text("fast")
+			})
+			api.GET("/slow", func(ctx *Context) {
+				<-ctx.Req.Context().Done()
+				cancelled <- ctx.Req.Context().Err()
+				_, err := ctx.Writer.Write([]byte("late"))
+				late <- err
+			})
+		})
+		e.GET("/free", func(ctx *Context) {
+			_, ok := ctx.Req.Context().Deadline()
+			ctx.Text(map[bool]string{true: "deadline", false: "none"}[ok])
+		})
+
+		w := performRequest(e, "GET", "/api/fast")
+		So(w.Code, ShouldEqual, 200)
+		So(w.Body.String(), ShouldEqual, "fast")
+		So(w.Header().Get("X-Fast"), ShouldEqual, "1")
+
+		w = performRequest(e, "GET", "/api/slow")
+		So(w.Code, ShouldEqual, http.StatusServiceUnavailable)
+		So(w.Body.String(), ShouldEqual, http.StatusText(http.StatusServiceUnavailable))
+		So(<-cancelled, ShouldNotBeNil)
+		So(<-late, ShouldEqual, ErrHandlerTimeout)
+		So(w.Body.String(), ShouldEqual, http.StatusText(http.StatusServiceUnavailable))
+
+		So(performRequest(e, "GET", "/free").Body.String(), ShouldEqual, "none")
+	})
+
+	Convey("Before hooks run on the handlers' response only", t, func() {
+		e := New()
+		e.Use(Timeout(20 * time.Millisecond))
+		e.Use(func(ctx *Context) {
+			ctx.Writer.Before(func(w ResponseWriter) { w.Header().Set("X-Before", "1") })
+		})
+		e.GET("/fast", func(ctx *Context) { ctx.Text("fast") })
+		e.GET("/slow", func(ctx *Context) { <-ctx.Req.Context().Done() })
+
+		w := performRequest(e, "GET", "/fast")
+		So(w.Body.String(), ShouldEqual, "fast")
+		So(w.Header().Get("X-Before"), ShouldEqual, "1")
+
+		w = performRequest(e, "GET", "/slow")
+		So(w.Code, ShouldEqual, http.StatusServiceUnavailable)
+		So(w.Header().Get("X-Before"), ShouldEqual, "")
+	})
+
+	Convey("Timeout status and body are configurable", t, func() {
+		e := New()
+		e.GET("/slow", Timeout(10*time.Millisecond, TimeoutOptions{
+			Status: http.StatusGatewayTimeout,
+			Body:   "upstream too slow",
+		}), func(ctx *Context) {
+			<-ctx.Req.Context().Done()
+		})
+
+		w := performRequest(e, "GET", "/slow")
+		So(w.Code, ShouldEqual, http.StatusGatewayTimeout)
+		So(w.Body.String(), ShouldEqual, "upstream too slow")
+	})
+
+	Convey("Handlers under Timeout keep the request and trace ids", t, func() {
+		w := &recordWriter{}
+		logger.AddFilter("timeout_test", logger.INFO, w)
+		defer delete(logger.Global, "timeout_test")
+		tracer := trace.NewTracer(trace.Options{})
+		defer tracer.Shutdown(context.Background())
+
+		e := New()
+		e.Use(RequestID(func() string { return "rid" }), Tracing(TracingOptions{Tracer: tracer}), Timeout(time.Second))
+		e.GET("/", func(ctx *Context) { ctx.Logger().Info("under timeout") })
+		performRequest(e, "GET", "/")
+
+		So(len(w.records), ShouldEqual, 1)
+		So(w.records[0].RequestID, ShouldEqual, "rid")
+		So(w.records[0].TraceID, ShouldNotBeEmpty)
+		So(w.records[0].Message, ShouldEqual, "under timeout")
+	})
+}
@@ -0,0 +1,213 @@
+package httpsvr
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hydah/golib/logger"
+)
+
+// TLSOptions configures HTTPS on a listener.
+type TLSOptions struct {
+	// CertFile and KeyFile are the PEM encoded certificate chain and key,
+	// they are reloaded on SIGHUP and, with ReloadInterval, when changed.
+	CertFile string
+	KeyFile  string
+	// ReloadInterval is how often the files are checked for changes, 0
+	// only reloads on SIGHUP.
+	ReloadInterval time.Duration
+	// MinVersion is the minimum TLS version, default TLS 1.2.
+	MinVersion uint16
+	// CipherSuites restricts the TLS 1.2 cipher suites, default Go's.
+	CipherSuites []uint16
+	// NextProtos are the ALPN protocols, default h2 and http/1.1.
+	NextProtos []string
+	// ClientCAFile is a PEM bundle of the CAs client certificates are
+	// verified against, see Context.PeerIdentity.
+	ClientCAFile string
+	// ClientAuth is the client certificate policy, default
+	// RequireAndVerifyClientCert when ClientCAFile is set.
+	ClientAuth tls.ClientAuthType
+}
+
+// Config builds a tls.Config serving the certificate of a CertReloader.
+func (o *TLSOptions) Config() (*tls.Config, *CertReloader, error) {
+	reloader, err := NewCertReloader(o.CertFile, o.KeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg := &tls.Config{
+		GetCertificate: reloader.GetCertificate,
+		MinVersion:     o.MinVersion,
+		CipherSuites:   o.CipherSuites,
+		NextProtos:     o.NextProtos,
+		ClientAuth:     o.ClientAuth,
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+	if o.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(o.ClientCAFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		cfg.ClientCAs = x509.NewCertPool()
+		if !cfg.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, nil, errors.New("httpsvr: no certificate in " + o.ClientCAFile)
+		}
+		if cfg.ClientAuth == tls.NoClientCert {
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return cfg, reloader, nil
+}
+
+// CertReloader serves a certificate loaded from files and swaps it when the
+// files change, a failed reload keeps the current certificate.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	lock    sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// NewCertReloader loads the certificate of certFile and keyFile.
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the certificate files again.
+func (r *CertReloader) Reload() error {
+	modTime := r.filesModTime()
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.lock.Lock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.lock.Unlock()
+	return nil
+}
+
+// GetCertificate is the tls.Config.GetCertificate serving the current
+// certificate.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.cert, nil
+}
+
+// filesModTime returns the latest modification time of the files.
+func (r *CertReloader) filesModTime() time.Time {
+	var latest time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		if fi, err := os.Stat(file); err == nil && fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest
+}
+
+// watch reloads the certificate whenever the files change until done is
+// closed.
+func (r *CertReloader) watch(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.lock.RLock()
+			modTime := r.modTime
+			r.lock.RUnlock()
+			if r.filesModTime().Equal(modTime) {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				logger.Error("reload certificate %s: %v", r.certFile, err)
+				continue
+			}
+			logger.Info("reloaded certificate %s", r.certFile)
+		case <-done:
+			return
+		}
+	}
+}
+
+// addCertReloader makes SIGHUP reload r until the server is shut down.
+func (s *HTTPServer) addCertReloader(r *CertReloader, interval time.Duration) {
+	s.lock.Lock()
+	s.reloaders = append(s.reloaders, r)
+	s.lock.Unlock()
+	if interval > 0 {
+		go r.watch(interval, s.done)
+	}
+	s.reloadOnce.Do(func() {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, syscall.SIGHUP)
+		go func() {
+			defer signal.Stop(ch)
+			for {
+				select {
+				case <-ch:
+					s.ReloadCertificates()
+				case <-s.done:
+					return
+				}
+			}
+		}()
+	})
+}
+
+// ReloadCertificates reloads the certificates of every HTTPS listener, it is
+// called on SIGHUP.
+func (s *HTTPServer) ReloadCertificates() {
+	s.lock.Lock()
+	reloaders := s.reloaders
+	s.lock.Unlock()
+	for _, r := range reloaders {
+		if err := r.Reload(); err != nil {
+			logger.Error("[%s] reload certificate %s: %v", s.engine.AppName, r.certFile, err)
+			continue
+		}
+		logger.Info("[%s] reloaded certificate %s", s.engine.AppName, r.certFile)
+	}
+}
+
+// PeerCertificate returns the verified client certificate of a mutual TLS
+// request, or nil.
+func (c *Context) PeerCertificate() *x509.Certificate {
+	if c.Req.TLS == nil || len(c.Req.TLS.VerifiedChains) == 0 || len(c.Req.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return c.Req.TLS.VerifiedChains[0][0]
+}
+
+// PeerIdentity returns the identity of the verified client certificate: its
+// first URI SAN (e.g. a SPIFFE ID), else its first DNS SAN, else its common
+// name. It returns "" when the client is not verified.
+func (c *Context) PeerIdentity() string {
+	cert := c.PeerCertificate()
+	switch {
+	case cert == nil:
+		return ""
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	}
+	return cert.Subject.CommonName
+}
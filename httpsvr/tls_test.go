@@ -0,0 +1,191 @@
+package httpsvr
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_TLS(t *testing.T) {
+	Convey("Certificates are reloaded when the files change", t, func() {
+		dir, err := ioutil.TempDir("", "httpsvr")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		ca := newTestCA()
+		certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+		ca.issue(1, &x509.Certificate{IPAddresses: []net.IP{net.ParseIP("127.0.0.1")}}).write(certFile, keyFile)
+
+		s := NewHTTPServer()
+		s.engine.GET("/", func(ctx *Context) { ctx.Text("ok") })
+		addr := freeAddr()
+		go s.RunListeners(ListenConfig{Addr: addr, TLS: &TLSOptions{
+			CertFile:       certFile,
+			KeyFile:        keyFile,
+			ReloadInterval: 10 * time.Millisecond,
+		}})
+		defer s.Shutdown(context.Background())
+		waitListening(addr)
+
+		So(servedSerial(addr, ca), ShouldEqual, 1)
+
+		ca.issue(2, &x509.Certificate{IPAddresses: []net.IP{net.ParseIP("127.0.0.1")}}).write(certFile, keyFile)
+		future := time.Now().Add(time.Second)
+		os.Chtimes(certFile, future, future)
+		serial := int64(0)
+		for i := 0; i < 100 && serial != 2; i++ {
+			time.Sleep(10 * time.Millisecond)
+			serial = servedSerial(addr, ca)
+		}
+		So(serial, ShouldEqual, 2)
+
+		Convey("A broken file keeps the current certificate", func() {
+			ioutil.WriteFile(certFile, []byte("garbage"), 0600)
+			s.ReloadCertificates()
+			So(servedSerial(addr, ca), ShouldEqual, 2)
+		})
+	})
+
+	Convey("Mutual TLS exposes the verified peer identity", t, func() {
+		dir, err := ioutil.TempDir("", "httpsvr")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		ca := newTestCA()
+		certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+		ca.issue(1, &x509.Certificate{IPAddresses: []net.IP{net.ParseIP("127.0.0.1")}}).write(certFile, keyFile)
+		caFile := filepath.Join(dir, "ca.pem")
+		ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0600)
+
+		s := NewHTTPServer()
+		s.engine.GET("/", func(ctx *Context) { ctx.Text(ctx.PeerIdentity()) })
+		addr := freeAddr()
+		go s.RunListeners(ListenConfig{Addr: addr, TLS: &TLSOptions{
+			CertFile:     certFile,
+			KeyFile:      keyFile,
+			ClientCAFile: caFile,
+		}})
+		defer s.Shutdown(context.Background())
+		waitListening(addr)
+
+		spiffe, _ := url.Parse("spiffe://example.org/billing")
+		client := ca.issue(3, &x509.Certificate{
+			Subject:     pkix.Name{CommonName: "billing"},
+			URIs:        []*url.URL{spiffe},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		So(get(ca.client(&client.tls), "https://"+addr+"/"), ShouldEqual, "spiffe://example.org/billing")
+
+		_, err = ca.client(nil).Get("https://" + addr + "/")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("TLSOptions defaults to TLS 1.2 and verified client certificates", t, func() {
+		dir, err := ioutil.TempDir("", "httpsvr")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		ca := newTestCA()
+		certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+		ca.issue(1, &x509.Certificate{}).write(certFile, keyFile)
+
+		cfg, _, err := (&TLSOptions{CertFile: certFile, KeyFile: keyFile, NextProtos: []string{"h2"}}).Config()
+		So(err, ShouldBeNil)
+		So(cfg.MinVersion, ShouldEqual, tls.VersionTLS12)
+		So(cfg.NextProtos, ShouldResemble, []string{"h2"})
+		So(cfg.ClientAuth, ShouldEqual, tls.NoClientCert)
+
+		_, _, err = (&TLSOptions{CertFile: certFile, KeyFile: keyFile, ClientCAFile: certFile}).Config()
+		So(err, ShouldBeNil)
+		_, _, err = (&TLSOptions{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile}).Config()
+		So(err, ShouldNotBeNil)
+	})
+}
+
+// servedSerial returns the serial number of the certificate served on addr.
+func servedSerial(addr string, ca *testCA) int64 {
+	conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: ca.pool()})
+	if err != nil {
+		return 0
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+}
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+type testCert struct {
+	tls tls.Certificate
+	der []byte
+	key *ecdsa.PrivateKey
+}
+
+func newTestCA() *testCA {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(100),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) issue(serial int64, tpl *x509.Certificate) *testCert {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tpl.SerialNumber = big.NewInt(serial)
+	tpl.NotBefore = time.Now().Add(-time.Hour)
+	tpl.NotAfter = time.Now().Add(time.Hour)
+	if tpl.ExtKeyUsage == nil {
+		tpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		panic(err)
+	}
+	return &testCert{tls: tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, der: der, key: key}
+}
+
+func (c *testCert) write(certFile, keyFile string) {
+	keyDER, _ := x509.MarshalECPrivateKey(c.key)
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+func (ca *testCA) client(cert *tls.Certificate) *http.Client {
+	cfg := &tls.Config{RootCAs: ca.pool()}
+	if cert != nil {
+		cfg.Certificates = []tls.Certificate{*cert}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+}
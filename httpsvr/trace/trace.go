@@ -0,0 +1,377 @@
+// Package trace implements W3C Trace Context propagation and records spans
+// exported through a pluggable Exporter.
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Headers of the W3C Trace Context.
+const (
+	HeaderTraceparent = "traceparent"
+	HeaderTracestate  = "tracestate"
+)
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+// SpanID identifies a span in a trace.
+type SpanID [8]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+
+// IsValid reports whether t is not all zeros.
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+// IsValid reports whether s is not all zeros.
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+// FlagSampled is the sampled bit of the trace flags.
+const FlagSampled byte = 0x01
+
+// SpanContext is what propagates across services.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+}
+
+// Sampled reports whether the trace is recorded.
+func (sc SpanContext) Sampled() bool { return sc.Flags&FlagSampled != 0 }
+
+// IsValid reports whether both ids are set.
+func (sc SpanContext) IsValid() bool { return sc.TraceID.IsValid() && sc.SpanID.IsValid() }
+
+// Traceparent formats sc as a traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + hex.EncodeToString([]byte{sc.Flags})
+}
+
+var errTraceparent = errors.New("trace: malformed traceparent")
+
+// ParseTraceparent parses a traceparent header value. Versions above 00 are
+// parsed as 00, ignoring trailing fields, as the specification asks.
+func ParseTraceparent(value string) (SpanContext, error) {
+	var sc SpanContext
+	value = strings.TrimSpace(value)
+	if len(value) < 55 || (len(value) > 55 && value[55] != '-') {
+		return sc, errTraceparent
+	}
+	if value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return sc, errTraceparent
+	}
+	version, err := hex.DecodeString(value[:2])
+	if err != nil || version[0] == 0xff || (version[0] == 0 && len(value) != 55) {
+		return sc, errTraceparent
+	}
+	if !isLowerHex(value[3:35]) || !isLowerHex(value[36:52]) || !isLowerHex(value[53:55]) {
+		return sc, errTraceparent
+	}
+	hex.Decode(sc.TraceID[:], []byte(value[3:35]))
+	hex.Decode(sc.SpanID[:], []byte(value[36:52]))
+	flags, _ := hex.DecodeString(value[53:55])
+	sc.Flags = flags[0]
+	if !sc.IsValid() {
+		return SpanContext{}, errTraceparent
+	}
+	return sc, nil
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !('0' <= s[i] && s[i] <= '9' || 'a' <= s[i] && s[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Extract reads the span context from the headers, it is invalid when the
+// headers carry none.
+func Extract(h http.Header) SpanContext {
+	sc, err := ParseTraceparent(h.Get(HeaderTraceparent))
+	if err != nil {
+		return SpanContext{}
+	}
+	sc.TraceState = strings.Join(h[http.CanonicalHeaderKey(HeaderTracestate)], ",")
+	return sc
+}
+
+// Inject writes sc into the headers, e.g. of an outgoing request.
+func Inject(h http.Header, sc SpanContext) {
+	if !sc.IsValid() {
+		return
+	}
+	h.Set(HeaderTraceparent, sc.Traceparent())
+	if sc.TraceState != "" {
+		h.Set(HeaderTracestate, sc.TraceState)
+	} else {
+		h.Del(HeaderTracestate)
+	}
+}
+
+// SpanKind is the role of a span.
+type SpanKind int
+
+// Kinds of spans, numbered as in OTLP.
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// StatusCode is the status of a span, numbered as in OTLP.
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+// Span is one timed operation of a trace.
+type Span struct {
+	Name          string
+	Kind          SpanKind
+	SpanContext   SpanContext
+	Parent        SpanID
+	Start         time.Time
+	End           time.Time
+	Attributes    map[string]interface{}
+	StatusCode    StatusCode
+	StatusMessage string
+
+	lock   sync.Mutex
+	tracer *Tracer
+	ended  bool
+}
+
+// SetAttribute sets an attribute, values are strings, bools, ints or
+// float64s.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.lock.Lock()
+	s.Attributes[key] = value
+	s.lock.Unlock()
+}
+
+// SetStatus sets the status of the span.
+func (s *Span) SetStatus(code StatusCode, message string) {
+	s.lock.Lock()
+	s.StatusCode, s.StatusMessage = code, message
+	s.lock.Unlock()
+}
+
+// RecordError marks the span as failed with err.
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.lock.Lock()
+	s.Attributes["error"] = true
+	s.Attributes["exception.message"] = err.Error()
+	s.StatusCode, s.StatusMessage = StatusError, err.Error()
+	s.lock.Unlock()
+}
+
+// Finish ends the span and hands it to the exporter when sampled, calling
+// it again does nothing.
+func (s *Span) Finish() {
+	s.lock.Lock()
+	if s.ended {
+		s.lock.Unlock()
+		return
+	}
+	s.ended = true
+	s.End = time.Now()
+	s.lock.Unlock()
+	if s.SpanContext.Sampled() {
+		s.tracer.enqueue(s)
+	}
+}
+
+// Exporter sends finished spans somewhere.
+type Exporter interface {
+	Export(ctx context.Context, spans []*Span) error
+	Shutdown(ctx context.Context) error
+}
+
+// Options configures a Tracer.
+type Options struct {
+	// Exporter receives the sampled spans, nothing is exported without.
+	Exporter Exporter
+	// Sampler decides whether a new trace is recorded, default always.
+	// Incoming traces keep the decision of the caller.
+	Sampler func(TraceID) bool
+	// BatchSize spans are exported at once, default 512.
+	BatchSize int
+	// FlushInterval is the longest a span waits for its batch, default 5s.
+	FlushInterval time.Duration
+	// QueueSize is the number of spans waiting for export, more are
+	// dropped, default 2048.
+	QueueSize int
+	// OnError is called when an export fails.
+	OnError func(error)
+}
+
+// Tracer starts spans and exports them in batches in the background.
+type Tracer struct {
+	opts  Options
+	queue chan *Span
+	flush chan chan struct{}
+	done  chan struct{}
+	// stopped is closed once run has exported the last spans
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// NewTracer starts a Tracer, Shutdown stops it.
+func NewTracer(opts Options) *Tracer {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 512
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 5 * time.Second
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 2048
+	}
+	t := &Tracer{
+		opts:    opts,
+		queue:   make(chan *Span, opts.QueueSize),
+		flush:   make(chan chan struct{}),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// Start starts a span as a child of parent, or of a new trace when parent is
+// invalid.
+func (t *Tracer) Start(name string, kind SpanKind, parent SpanContext) *Span {
+	sc := parent
+	if !parent.IsValid() {
+		sc = SpanContext{TraceID: newTraceID()}
+		if t.opts.Sampler == nil || t.opts.Sampler(sc.TraceID) {
+			sc.Flags = FlagSampled
+		}
+	}
+	sc.SpanID = newSpanID()
+	return &Span{
+		Name:        name,
+		Kind:        kind,
+		SpanContext: sc,
+		Parent:      parent.SpanID,
+		Start:       time.Now(),
+		Attributes:  make(map[string]interface{}),
+		tracer:      t,
+	}
+}
+
+func (t *Tracer) enqueue(s *Span) {
+	if t.opts.Exporter == nil {
+		return
+	}
+	select {
+	case t.queue <- s:
+	default:
+	}
+}
+
+func (t *Tracer) run() {
+	defer close(t.stopped)
+	ticker := time.NewTicker(t.opts.FlushInterval)
+	defer ticker.Stop()
+	var batch []*Span
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.opts.Exporter.Export(context.Background(), batch); err != nil && t.opts.OnError != nil {
+			t.opts.OnError(err)
+		}
+		batch = nil
+	}
+	drain := func() {
+		for {
+			select {
+			case s := <-t.queue:
+				batch = append(batch, s)
+			default:
+				export()
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case s := <-t.queue:
+			if batch = append(batch, s); len(batch) >= t.opts.BatchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case ack := <-t.flush:
+			drain()
+			close(ack)
+		case <-t.done:
+			drain()
+			return
+		}
+	}
+}
+
+// Flush exports the finished spans now.
+func (t *Tracer) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case t.flush <- ack:
+	case <-t.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown exports the finished spans and shuts the exporter down.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	t.once.Do(func() { close(t.done) })
+	select {
+	case <-t.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if t.opts.Exporter == nil {
+		return nil
+	}
+	return t.opts.Exporter.Shutdown(ctx)
+}
+
+func newTraceID() (id TraceID) {
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return
+}
+
+func newSpanID() (id SpanID) {
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return
+}
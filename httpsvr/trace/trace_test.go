@@ -0,0 +1,185 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func Test_Traceparent(t *testing.T) {
+	Convey("traceparent round trips", t, func() {
+		sc, err := ParseTraceparent(parent)
+		So(err, ShouldBeNil)
+		So(sc.TraceID.String(), ShouldEqual, "4bf92f3577b34da6a3ce929d0e0e4736")
+		So(sc.SpanID.String(), ShouldEqual, "00f067aa0ba902b7")
+		So(sc.Sampled(), ShouldBeTrue)
+		So(sc.Traceparent(), ShouldEqual, parent)
+
+		_, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future")
+		So(err, ShouldBeNil)
+	})
+
+	Convey("malformed traceparents are rejected", t, func() {
+		for _, bad := range []string{
+			"",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+			"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		} {
+			_, err := ParseTraceparent(bad)
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Extract and Inject carry the tracestate", t, func() {
+		h := http.Header{}
+		h.Set(HeaderTraceparent, parent)
+		h.Add(HeaderTracestate, "a=1")
+		h.Add(HeaderTracestate, "b=2")
+		sc := Extract(h)
+		So(sc.TraceState, ShouldEqual, "a=1,b=2")
+
+		out := http.Header{}
+		Inject(out, sc)
+		So(out.Get(HeaderTraceparent), ShouldEqual, parent)
+		So(out.Get(HeaderTracestate), ShouldEqual, "a=1,b=2")
+
+		So(Extract(http.Header{}).IsValid(), ShouldBeFalse)
+	})
+}
+
+type memoryExporter struct {
+	spans    chan []*Span
+	shutdown bool
+}
+
+func (e *memoryExporter) Export(ctx context.Context, spans []*Span) error {
+	e.spans <- spans
+	return nil
+}
+
+func (e *memoryExporter) Shutdown(ctx context.Context) error {
+	e.shutdown = true
+	return nil
+}
+
+func Test_Tracer(t *testing.T) {
+	Convey("Spans are exported in batches", t, func() {
+		exp := &memoryExporter{spans: make(chan []*Span, 10)}
+		tracer := NewTracer(Options{Exporter: exp, BatchSize: 2, FlushInterval: time.Hour})
+		sc, _ := ParseTraceparent(parent)
+
+		child := tracer.Start("child", SpanKindServer, sc)
+		So(child.SpanContext.TraceID, ShouldEqual, sc.TraceID)
+		So(child.Parent, ShouldEqual, sc.SpanID)
+		So(child.SpanContext.SpanID, ShouldNotEqual, sc.SpanID)
+		child.Finish()
+		child.Finish()
+		root := tracer.Start("root", SpanKindInternal, SpanContext{})
+		So(root.Parent.IsValid(), ShouldBeFalse)
+		root.Finish()
+
+		batch := <-exp.spans
+		So(len(batch), ShouldEqual, 2)
+		So(batch[0].Name, ShouldEqual, "child")
+
+		unsampled := sc
+		unsampled.Flags = 0
+		tracer.Start("dropped", SpanKindServer, unsampled).Finish()
+		tracer.Start("last", SpanKindServer, sc).Finish()
+		So(tracer.Shutdown(context.Background()), ShouldBeNil)
+		batch = <-exp.spans
+		So(len(batch), ShouldEqual, 1)
+		So(batch[0].Name, ShouldEqual, "last")
+		So(exp.shutdown, ShouldBeTrue)
+	})
+
+	Convey("The sampler decides for new traces", t, func() {
+		tracer := NewTracer(Options{Sampler: func(TraceID) bool { return false }})
+		defer tracer.Shutdown(context.Background())
+		So(tracer.Start("x", SpanKindServer, SpanContext{}).SpanContext.Sampled(), ShouldBeFalse)
+	})
+}
+
+func Test_Exporters(t *testing.T) {
+	sc, _ := ParseTraceparent(parent)
+	tracer := NewTracer(Options{})
+	defer tracer.Shutdown(context.Background())
+	span := tracer.Start("GET /users/:id", SpanKindServer, sc)
+	span.SetAttribute("http.status_code", 500)
+	span.SetAttribute("http.route", "/users/:id")
+	span.RecordError(errors.New("boom"))
+	span.Finish()
+
+	Convey("WriterExporter writes JSON lines", t, func() {
+		var buf bytes.Buffer
+		So(NewWriterExporter(&buf).Export(context.Background(), []*Span{span, span}), ShouldBeNil)
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		So(len(lines), ShouldEqual, 2)
+		var js map[string]interface{}
+		So(json.Unmarshal([]byte(lines[0]), &js), ShouldBeNil)
+		So(js["trace_id"], ShouldEqual, sc.TraceID.String())
+		So(js["parent_id"], ShouldEqual, sc.SpanID.String())
+		So(js["status_message"], ShouldEqual, "boom")
+	})
+
+	Convey("OTLPExporter posts OTLP JSON to the collector", t, func() {
+		var got map[string]interface{}
+		var auth string
+		collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth = r.Header.Get("Authorization")
+			body, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(body, &got)
+			w.Write([]byte("{}"))
+		}))
+		defer collector.Close()
+
+		exp := &OTLPExporter{
+			Endpoint:    collector.URL + "/v1/traces",
+			ServiceName: "users",
+			Headers:     map[string]string{"Authorization": "Bearer t"},
+		}
+		So(exp.Export(context.Background(), []*Span{span}), ShouldBeNil)
+		So(auth, ShouldEqual, "Bearer t")
+
+		rs := got["resourceSpans"].([]interface{})[0].(map[string]interface{})
+		resource := rs["resource"].(map[string]interface{})["attributes"].([]interface{})[0].(map[string]interface{})
+		So(resource["key"], ShouldEqual, "service.name")
+		So(resource["value"], ShouldResemble, map[string]interface{}{"stringValue": "users"})
+
+		ss := rs["scopeSpans"].([]interface{})[0].(map[string]interface{})
+		So(ss["scope"], ShouldResemble, map[string]interface{}{"name": InstrumentationScope})
+		s := ss["spans"].([]interface{})[0].(map[string]interface{})
+		So(s["traceId"], ShouldEqual, sc.TraceID.String())
+		So(s["parentSpanId"], ShouldEqual, sc.SpanID.String())
+		So(s["kind"], ShouldEqual, 2)
+		So(s["status"], ShouldResemble, map[string]interface{}{"code": float64(2), "message": "boom"})
+		attrs := s["attributes"].([]interface{})
+		So(attrs[3], ShouldResemble, map[string]interface{}{
+			"key": "http.status_code", "value": map[string]interface{}{"intValue": "500"},
+		})
+	})
+
+	Convey("OTLPExporter reports collector errors", t, func() {
+		collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "bad", http.StatusBadRequest)
+		}))
+		defer collector.Close()
+		err := (&OTLPExporter{Endpoint: collector.URL}).Export(context.Background(), []*Span{span})
+		So(err, ShouldNotBeNil)
+	})
+}
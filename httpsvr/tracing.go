@@ -0,0 +1,76 @@
+package httpsvr
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/hydah/golib/httpsvr/trace"
+	"github.com/hydah/golib/logger"
+)
+
+// SpanKey is the Context.Keys entry holding the server span.
+const SpanKey = "trace_span"
+
+// TracingOptions configures Tracing.
+type TracingOptions struct {
+	// Tracer starts and exports the spans.
+	Tracer *trace.Tracer
+	// SpanName names the span, default the method and the route pattern.
+	SpanName func(*Context) string
+}
+
+// Tracing returns a middleware continuing the trace of the traceparent and
+// tracestate headers, or starting a new one, with a server span per request.
+// The span carries the method, route, target, status and client address,
+// 5xx responses and panics mark it as failed. The response echoes the
+// traceparent of the span and the records logged through Context.Logger
+// carry its trace id.
+func Tracing(opts TracingOptions) HandlerFunc {
+	if opts.Tracer == nil {
+		panic("httpsvr: Tracing needs a Tracer")
+	}
+	if opts.SpanName == nil {
+		opts.SpanName = func(ctx *Context) string {
+			route := ctx.Route()
+			if route == "" {
+				route = unmatchedRoute
+			}
+			return ctx.Req.Method + " " + route
+		}
+	}
+	return func(ctx *Context) {
+		parent := trace.Extract(ctx.Req.Header)
+		span := opts.Tracer.Start(opts.SpanName(ctx), trace.SpanKindServer, parent)
+		span.SetAttribute("http.method", ctx.Req.Method)
+		span.SetAttribute("http.target", ctx.Req.URL.RequestURI())
+		span.SetAttribute("http.route", ctx.Route())
+		span.SetAttribute("net.peer.ip", ctx.ClientIP())
+		if ua := ctx.Req.UserAgent(); ua != "" {
+			span.SetAttribute("http.user_agent", ua)
+		}
+		ctx.Set(SpanKey, span)
+		trace.Inject(ctx.Writer.Header(), span.SpanContext)
+
+		ctx.Req = ctx.Req.WithContext(logger.WithTraceID(ctx.Req.Context(), span.SpanContext.TraceID.String()))
+		defer func() {
+			if err := recover(); err != nil {
+				span.RecordError(fmt.Errorf("panic: %v", err))
+				span.Finish()
+				panic(err)
+			}
+			status := ctx.Writer.Status()
+			span.SetAttribute("http.status_code", status)
+			if status >= 500 && span.StatusCode == trace.StatusUnset {
+				span.SetStatus(trace.StatusError, http.StatusText(status))
+			}
+			span.Finish()
+		}()
+		ctx.Next()
+	}
+}
+
+// Span returns the server span started by Tracing, or nil.
+func (c *Context) Span() *trace.Span {
+	span, _ := c.Keys[SpanKey].(*trace.Span)
+	return span
+}
@@ -0,0 +1,74 @@
+package httpsvr
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hydah/golib/httpsvr/trace"
+	"github.com/hydah/golib/logger"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type spanCollector struct {
+	spans chan *trace.Span
+}
+
+func (e *spanCollector) Export(ctx context.Context, spans []*trace.Span) error {
+	for _, s := range spans {
+		e.spans <- s
+	}
+	return nil
+}
+
+func (e *spanCollector) Shutdown(ctx context.Context) error { return nil }
+
+func Test_Tracing(t *testing.T) {
+	Convey("Tracing continues the incoming trace with a server span", t, func() {
+		exp := &spanCollector{spans: make(chan *trace.Span, 10)}
+		tracer := trace.NewTracer(trace.Options{Exporter: exp, BatchSize: 1})
+		defer tracer.Shutdown(context.Background())
+		w := &recordWriter{}
+		logger.AddFilter("tracing_test", logger.INFO, w)
+		defer delete(logger.Global, "tracing_test")
+
+		e := New()
+		e.Use(Tracing(TracingOptions{Tracer: tracer}))
+		e.GET("/users/:id", func(ctx *Context) {
+			ctx.Logger().Info("loading user")
+			ctx.Text("user")
+		})
+		e.GET("/fail", func(ctx *Context) { ctx.Text("oops", 503) })
+
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/users/7?x=1", nil)
+		req.Header.Set(trace.HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		req.Header.Set(trace.HeaderTracestate, "vendor=1")
+		e.ServeHTTP(rec, req)
+
+		span := <-exp.spans
+		So(span.Name, ShouldEqual, "GET /users/:id")
+		So(span.Kind, ShouldEqual, trace.SpanKindServer)
+		So(span.SpanContext.TraceID.String(), ShouldEqual, "4bf92f3577b34da6a3ce929d0e0e4736")
+		So(span.Parent.String(), ShouldEqual, "00f067aa0ba902b7")
+		So(span.Attributes["http.route"], ShouldEqual, "/users/:id")
+		So(span.Attributes["http.target"], ShouldEqual, "/users/7?x=1")
+		So(span.Attributes["http.status_code"], ShouldEqual, 200)
+		So(span.StatusCode, ShouldEqual, trace.StatusUnset)
+
+		echoed := trace.Extract(rec.Header())
+		So(echoed.SpanID, ShouldEqual, span.SpanContext.SpanID)
+		So(echoed.TraceState, ShouldEqual, "vendor=1")
+
+		So(w.records[len(w.records)-1].TraceID, ShouldEqual, "4bf92f3577b34da6a3ce929d0e0e4736")
+		So(w.records[len(w.records)-1].Message, ShouldEqual, "loading user")
+
+		req, _ = http.NewRequest("GET", "/fail", nil)
+		e.ServeHTTP(httptest.NewRecorder(), req)
+		span = <-exp.spans
+		So(span.Parent.IsValid(), ShouldBeFalse)
+		So(span.StatusCode, ShouldEqual, trace.StatusError)
+		So(span.Attributes["http.status_code"], ShouldEqual, 503)
+	})
+}
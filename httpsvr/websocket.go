@@ -0,0 +1,707 @@
+package httpsvr
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	HeaderUpgrade                = "Upgrade"
+	HeaderConnection             = "Connection"
+	HeaderSecWebSocketKey        = "Sec-WebSocket-Key"
+	HeaderSecWebSocketVersion    = "Sec-WebSocket-Version"
+	HeaderSecWebSocketAccept     = "Sec-WebSocket-Accept"
+	HeaderSecWebSocketProtocol   = "Sec-WebSocket-Protocol"
+	HeaderSecWebSocketExtensions = "Sec-WebSocket-Extensions"
+)
+
+// The message types, RFC 6455 section 11.8.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+)
+
+// The close codes, RFC 6455 section 7.4.1.
+const (
+	CloseNormalClosure       = 1000
+	CloseGoingAway           = 1001
+	CloseProtocolError       = 1002
+	CloseUnsupportedData     = 1003
+	CloseNoStatusReceived    = 1005
+	CloseAbnormalClosure     = 1006
+	CloseInvalidPayload      = 1007
+	ClosePolicyViolation     = 1008
+	CloseMessageTooBig       = 1009
+	CloseInternalServerError = 1011
+)
+
+const (
+	DefaultWebSocketReadLimit    = 1 << 20
+	DefaultWebSocketWriteTimeout = 10 * time.Second
+
+	websocketGUID      = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	websocketDeflate   = "permessage-deflate"
+	websocketCloseWait = 5 * time.Second
+)
+
+var (
+	ErrWebSocketClosed    = errors.New("httpsvr: websocket is closed")
+	ErrWebSocketReadLimit = errors.New("httpsvr: websocket message exceeds the read limit")
+)
+
+// CloseError is returned by ReadMessage when the peer closes the
+// connection, Code is CloseNoStatusReceived for a close without code, and
+// CloseAbnormalClosure when the connection is lost.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	s := "httpsvr: websocket closed with " + strconv.Itoa(e.Code)
+	if e.Text != "" {
+		s += ": " + e.Text
+	}
+	return s
+}
+
+// WebSocketOptions configures UpgradeWebSocket.
+type WebSocketOptions struct {
+	// AllowOrigins are the origins allowed to connect, as in CORSOptions.
+	// Without AllowOrigins and AllowOriginFunc the origin must be the host of
+	// the request. Requests without an Origin header, from other than
+	// browsers, are always allowed.
+	AllowOrigins []string
+	// AllowOriginFunc allows the origins it returns true for, in addition to
+	// AllowOrigins.
+	AllowOriginFunc func(origin string) bool
+	// Subprotocols are the supported subprotocols in order of preference, the
+	// first one the client asks for is chosen.
+	Subprotocols []string
+	// ReadLimit is the maximum size of a message, decompressed, default
+	// DefaultWebSocketReadLimit. Larger messages close the connection with
+	// CloseMessageTooBig.
+	ReadLimit int64
+	// WriteTimeout bounds every write, default DefaultWebSocketWriteTimeout.
+	WriteTimeout time.Duration
+	// PingInterval sends a ping at this interval, the connection fails when
+	// nothing is read within PongWait, default twice PingInterval. The pongs
+	// are read by ReadMessage, so a connection with keepalive must be read.
+	PingInterval time.Duration
+	PongWait     time.Duration
+	// Compression negotiates permessage-deflate, RFC 7692, when the client
+	// offers it. The messages are compressed at CompressionLevel, default
+	// flate.DefaultCompression, without context takeover.
+	Compression      bool
+	CompressionLevel int
+}
+
+// WebSocketConn is a WebSocket connection. One goroutine may read while
+// others write.
+type WebSocketConn struct {
+	conn         net.Conn
+	br           *bufio.Reader
+	bw           *bufio.Writer
+	subprotocol  string
+	compress     bool
+	level        int
+	readLimit    int64
+	writeTimeout time.Duration
+	pongWait     time.Duration
+
+	// reading holds a value while a goroutine reads, a mutex CloseWith can
+	// try to take.
+	reading chan struct{}
+	writeMu sync.Mutex
+	// closeSent is set once the close frame is written, under writeMu.
+	closeSent bool
+	// closed is closed when the close of the peer is read.
+	closed    chan struct{}
+	closeOnce sync.Once
+	done      chan struct{}
+	doneOnce  sync.Once
+}
+
+// UpgradeWebSocket performs the WebSocket handshake, RFC 6455, and takes
+// over the connection of the request. When the handshake fails, the error
+// status is written, the context aborted and the error returned.
+//
+// The handlers setting headers before it, e.g. cookies, have them sent with
+// the handshake. Hijacked connections are not closed by HTTPServer.Shutdown,
+// close them with CloseGoingAway from an OnShutdown hook.
+func (c *Context) UpgradeWebSocket(opts ...WebSocketOptions) (*WebSocketConn, error) {
+	var o WebSocketOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	req := c.Req
+	fail := func(status int, reason string) (*WebSocketConn, error) {
+		c.Writer.WriteHeader(status)
+		c.Abort()
+		return nil, errors.New("httpsvr: websocket handshake: " + reason)
+	}
+	if req.Method != "GET" {
+		c.SetHeader("Allow", "GET")
+		return fail(http.StatusMethodNotAllowed, "method is not GET")
+	}
+	if !headerHasToken(req.Header, HeaderConnection, "upgrade") || !headerHasToken(req.Header, HeaderUpgrade, "websocket") {
+		return fail(http.StatusBadRequest, "not a websocket upgrade")
+	}
+	if req.Header.Get(HeaderSecWebSocketVersion) != "13" {
+		c.SetHeader(HeaderSecWebSocketVersion, "13")
+		return fail(http.StatusUpgradeRequired, "unsupported version")
+	}
+	key := req.Header.Get(HeaderSecWebSocketKey)
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return fail(http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+	if !websocketOriginAllowed(req, &o) {
+		return fail(http.StatusForbidden, "origin not allowed")
+	}
+
+	ws := &WebSocketConn{
+		level:        o.CompressionLevel,
+		readLimit:    o.ReadLimit,
+		writeTimeout: o.WriteTimeout,
+		pongWait:     o.PongWait,
+		reading:      make(chan struct{}, 1),
+		closed:       make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	if ws.readLimit == 0 {
+		ws.readLimit = DefaultWebSocketReadLimit
+	}
+	if ws.writeTimeout == 0 {
+		ws.writeTimeout = DefaultWebSocketWriteTimeout
+	}
+	if ws.level == 0 {
+		ws.level = flate.DefaultCompression
+	}
+	if ws.pongWait == 0 {
+		ws.pongWait = 2 * o.PingInterval
+	}
+	if o.Compression {
+		ws.compress = negotiateDeflate(req.Header)
+	}
+	ws.subprotocol = negotiateSubprotocol(req.Header, o.Subprotocols)
+
+	var b bytes.Buffer
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	b.WriteString(HeaderSecWebSocketAccept + ": " + websocketAccept(key) + "\r\n")
+	if ws.subprotocol != "" {
+		b.WriteString(HeaderSecWebSocketProtocol + ": " + ws.subprotocol + "\r\n")
+	}
+	if ws.compress {
+		b.WriteString(HeaderSecWebSocketExtensions + ": " + websocketDeflate + "; server_no_context_takeover; client_no_context_takeover\r\n")
+	}
+	for k, vs := range c.Writer.Header() {
+		switch k {
+		case HeaderContentType, HeaderContentLength, HeaderContentEncoding, "Transfer-Encoding", HeaderUpgrade, HeaderConnection:
+			continue
+		}
+		for _, v := range vs {
+			b.WriteString(k + ": " + strings.NewReplacer("\r", "", "\n", "").Replace(v) + "\r\n")
+		}
+	}
+	b.WriteString("\r\n")
+
+	// the protocol switch bypasses the writers of the middlewares, but for
+	// Timeout which still writes its own response
+	if c.underTimeout {
+		return fail(http.StatusInternalServerError, ErrHijackTimeout.Error())
+	}
+	conn, brw, err := c.writer.Hijack()
+	if err != nil {
+		return fail(http.StatusInternalServerError, err.Error())
+	}
+	c.writer.status = http.StatusSwitchingProtocols
+	c.writer.size = 0
+	c.Abort()
+	conn.SetDeadline(time.Time{})
+	conn.SetWriteDeadline(time.Now().Add(ws.writeTimeout))
+	if _, err := conn.Write(b.Bytes()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetWriteDeadline(time.Time{})
+
+	ws.conn = conn
+	ws.br = brw.Reader
+	ws.bw = bufio.NewWriter(conn)
+	if o.PingInterval > 0 {
+		conn.SetReadDeadline(time.Now().Add(ws.pongWait))
+		go ws.keepalive(o.PingInterval)
+	}
+	return ws, nil
+}
+
+// Subprotocol returns the negotiated subprotocol.
+func (c *WebSocketConn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// Compressed returns whether permessage-deflate was negotiated.
+func (c *WebSocketConn) Compressed() bool {
+	return c.compress
+}
+
+// RemoteAddr returns the address of the client.
+func (c *WebSocketConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// ReadMessage reads the next text or binary message. Pings are answered
+// and the close of the peer is answered and returned as a *CloseError.
+func (c *WebSocketConn) ReadMessage() (messageType int, data []byte, err error) {
+	c.reading <- struct{}{}
+	defer func() { <-c.reading }()
+	return c.readMessage()
+}
+
+// ReadJSON reads the next message into v.
+func (c *WebSocketConn) ReadJSON(v interface{}) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// WriteMessage writes a message of messageType, in one frame. Pings and
+// pongs carry at most 125 bytes, close with Close.
+func (c *WebSocketConn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case TextMessage, BinaryMessage:
+	case PingMessage, PongMessage:
+		if len(data) > 125 {
+			return errors.New("httpsvr: websocket control message exceeds 125 bytes")
+		}
+	default:
+		return errors.New("httpsvr: websocket message type " + strconv.Itoa(messageType) + " is not writable")
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrWebSocketClosed
+	}
+	return c.writeFrame(messageType, data)
+}
+
+// WriteJSON writes v as a text message.
+func (c *WebSocketConn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(TextMessage, data)
+}
+
+// Close closes the connection with CloseNormalClosure.
+func (c *WebSocketConn) Close() error {
+	return c.CloseWith(CloseNormalClosure, "")
+}
+
+// CloseWith performs the close handshake with code and reason, it waits a
+// while for the close of the peer, read by the reading goroutine, or here
+// when no goroutine reads, then closes the connection.
+func (c *WebSocketConn) CloseWith(code int, reason string) error {
+	err := c.writeClose(code, reason)
+	select {
+	case c.reading <- struct{}{}:
+		c.conn.SetReadDeadline(time.Now().Add(websocketCloseWait))
+		for {
+			if _, _, err := c.readMessage(); err != nil {
+				break
+			}
+		}
+		<-c.reading
+	default:
+		select {
+		case <-c.closed:
+		case <-time.After(websocketCloseWait):
+		}
+	}
+	c.shutdown()
+	if err == ErrWebSocketClosed {
+		return nil
+	}
+	return err
+}
+
+func (c *WebSocketConn) keepalive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.WriteMessage(PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// shutdown closes the network connection.
+func (c *WebSocketConn) shutdown() {
+	c.doneOnce.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// fail closes the connection with code after a violation of the peer.
+func (c *WebSocketConn) fail(code int, err error) error {
+	c.writeClose(code, "")
+	c.shutdown()
+	return err
+}
+
+func (c *WebSocketConn) writeClose(code int, reason string) error {
+	data := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(data, uint16(code))
+	data = append(data, reason...)
+	if len(data) > 125 {
+		data = data[:125]
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrWebSocketClosed
+	}
+	c.closeSent = true
+	return c.writeFrame(CloseMessage, data)
+}
+
+// writeFrame writes a frame under writeMu, server frames are not masked.
+func (c *WebSocketConn) writeFrame(opcode int, data []byte) error {
+	b0 := byte(opcode) | 0x80
+	if c.compress && (opcode == TextMessage || opcode == BinaryMessage) {
+		compressed, err := deflateMessage(data, c.level)
+		if err != nil {
+			return err
+		}
+		data = compressed
+		b0 |= 0x40
+	}
+	header := make([]byte, 2, 10)
+	header[0] = b0
+	switch n := len(data); {
+	case n <= 125:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = header[:4]
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header[1] = 127
+		header = header[:10]
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	c.bw.Write(header)
+	c.bw.Write(data)
+	return c.bw.Flush()
+}
+
+type websocketFrame struct {
+	fin        bool
+	compressed bool
+	opcode     int
+	payload    []byte
+}
+
+// readFrame reads a frame of at most limit bytes of payload.
+func (c *WebSocketConn) readFrame(limit int64) (*websocketFrame, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(c.br, header[:2]); err != nil {
+		return nil, err
+	}
+	f := &websocketFrame{
+		fin:        header[0]&0x80 != 0,
+		compressed: header[0]&0x40 != 0,
+		opcode:     int(header[0] & 0x0f),
+	}
+	if header[0]&0x30 != 0 {
+		return nil, c.fail(CloseProtocolError, errors.New("httpsvr: websocket reserved bits are set"))
+	}
+	if header[1]&0x80 == 0 {
+		return nil, c.fail(CloseProtocolError, errors.New("httpsvr: websocket client frame is not masked"))
+	}
+	n := int64(header[1] & 0x7f)
+	switch n {
+	case 126:
+		if _, err := io.ReadFull(c.br, header[:2]); err != nil {
+			return nil, err
+		}
+		n = int64(binary.BigEndian.Uint16(header[:2]))
+	case 127:
+		if _, err := io.ReadFull(c.br, header[:8]); err != nil {
+			return nil, err
+		}
+		n = int64(binary.BigEndian.Uint64(header[:8]))
+		if n < 0 {
+			return nil, c.fail(CloseProtocolError, errors.New("httpsvr: websocket frame length is invalid"))
+		}
+	}
+	if f.opcode >= CloseMessage {
+		if !f.fin || n > 125 {
+			return nil, c.fail(CloseProtocolError, errors.New("httpsvr: websocket control frame is fragmented or too long"))
+		}
+	} else if n > limit {
+		return nil, c.fail(CloseMessageTooBig, ErrWebSocketReadLimit)
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return nil, err
+	}
+	f.payload = make([]byte, n)
+	if _, err := io.ReadFull(c.br, f.payload); err != nil {
+		return nil, err
+	}
+	for i := range f.payload {
+		f.payload[i] ^= mask[i%4]
+	}
+	if c.pongWait > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.pongWait))
+	}
+	return f, nil
+}
+
+// readMessage reads the next message while holding reading.
+func (c *WebSocketConn) readMessage() (int, []byte, error) {
+	var (
+		messageType int
+		compressed  bool
+		message     []byte
+	)
+	for {
+		f, err := c.readFrame(c.readLimit - int64(len(message)))
+		if err != nil {
+			select {
+			case <-c.done:
+				// closed here, by CloseWith or a failure
+				if _, ok := err.(*net.OpError); ok {
+					err = ErrWebSocketClosed
+				}
+			default:
+				c.shutdown()
+				if err == io.EOF || err == io.ErrUnexpectedEOF {
+					err = &CloseError{Code: CloseAbnormalClosure, Text: err.Error()}
+				}
+			}
+			return 0, nil, err
+		}
+		if f.compressed && (!c.compress || f.opcode == 0 || f.opcode >= CloseMessage) {
+			return 0, nil, c.fail(CloseProtocolError, errors.New("httpsvr: websocket frame is unexpectedly compressed"))
+		}
+
+		switch f.opcode {
+		case PingMessage:
+			c.writeMu.Lock()
+			if !c.closeSent {
+				c.writeFrame(PongMessage, f.payload)
+			}
+			c.writeMu.Unlock()
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+			return 0, nil, c.readClose(f.payload)
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, errors.New("httpsvr: websocket message is interleaved"))
+			}
+			messageType, compressed = f.opcode, f.compressed
+		case 0:
+			if messageType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, errors.New("httpsvr: websocket continuation without message"))
+			}
+		default:
+			return 0, nil, c.fail(CloseProtocolError, errors.New("httpsvr: websocket opcode "+strconv.Itoa(f.opcode)+" is unknown"))
+		}
+
+		message = append(message, f.payload...)
+		if !f.fin {
+			continue
+		}
+		if compressed {
+			if message, err = inflateMessage(message, c.readLimit); err == ErrWebSocketReadLimit {
+				return 0, nil, c.fail(CloseMessageTooBig, err)
+			} else if err != nil {
+				return 0, nil, c.fail(CloseInvalidPayload, err)
+			}
+		}
+		if messageType == TextMessage && !utf8.Valid(message) {
+			return 0, nil, c.fail(CloseInvalidPayload, errors.New("httpsvr: websocket text message is not UTF-8"))
+		}
+		return messageType, message, nil
+	}
+}
+
+// readClose answers the close frame of the peer and closes the connection.
+func (c *WebSocketConn) readClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+	switch {
+	case len(payload) == 1:
+		return c.fail(CloseProtocolError, errors.New("httpsvr: websocket close frame is invalid"))
+	case len(payload) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Text = string(payload[2:])
+		if !validCloseCode(closeErr.Code) || !utf8.Valid(payload[2:]) {
+			return c.fail(CloseProtocolError, errors.New("httpsvr: websocket close frame is invalid"))
+		}
+	}
+	code := closeErr.Code
+	if code == CloseNoStatusReceived {
+		code = CloseNormalClosure
+	}
+	c.writeClose(code, "")
+	c.closeOnce.Do(func() { close(c.closed) })
+	c.shutdown()
+	return closeErr
+}
+
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 3000 && code <= 4999:
+		return true
+	case code < 1000 || code > 1011:
+		return false
+	}
+	return code != 1004 && code != CloseNoStatusReceived && code != CloseAbnormalClosure
+}
+
+// deflateTail ends the deflate blocks of a message, RFC 7692 section 7.2.1.
+var deflateTail = []byte{0x00, 0x00, 0xff, 0xff}
+
+func deflateMessage(data []byte, level int) ([]byte, error) {
+	var b bytes.Buffer
+	w, err := flate.NewWriter(&b, level)
+	if err != nil {
+		return nil, err
+	}
+	w.Write(data)
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), deflateTail), nil
+}
+
+func inflateMessage(data []byte, limit int64) ([]byte, error) {
+	// the final empty block stops the reader at the end of the message
+	r := flate.NewReader(io.MultiReader(bytes.NewReader(data), bytes.NewReader(deflateTail), bytes.NewReader([]byte{0x01, 0x00, 0x00, 0xff, 0xff})))
+	defer r.Close()
+	message, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(message)) > limit {
+		return nil, ErrWebSocketReadLimit
+	}
+	return message, nil
+}
+
+func websocketAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func websocketOriginAllowed(req *http.Request, o *WebSocketOptions) bool {
+	origin := req.Header.Get(HeaderOrigin)
+	if origin == "" {
+		return true
+	}
+	if len(o.AllowOrigins) == 0 && o.AllowOriginFunc == nil {
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, req.Host)
+	}
+	for _, pattern := range o.AllowOrigins {
+		if pattern == "*" || matchOrigin(pattern, origin) {
+			return true
+		}
+	}
+	return o.AllowOriginFunc != nil && o.AllowOriginFunc(origin)
+}
+
+// headerHasToken reports whether the comma separated values of the header
+// contain token.
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, v := range header.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func negotiateSubprotocol(header http.Header, supported []string) string {
+	var offered []string
+	for _, v := range header.Values(HeaderSecWebSocketProtocol) {
+		for _, p := range strings.Split(v, ",") {
+			offered = append(offered, strings.TrimSpace(p))
+		}
+	}
+	for _, s := range supported {
+		for _, p := range offered {
+			if p == s {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// negotiateDeflate reports whether the client offers a permessage-deflate
+// the server supports, without context takeover and with the full window.
+func negotiateDeflate(header http.Header) bool {
+	for _, v := range header.Values(HeaderSecWebSocketExtensions) {
+	offers:
+		for _, offer := range strings.Split(v, ",") {
+			params := strings.Split(offer, ";")
+			if strings.TrimSpace(params[0]) != websocketDeflate {
+				continue
+			}
+			for _, param := range params[1:] {
+				name, value := strings.TrimSpace(param), ""
+				if i := strings.Index(name, "="); i >= 0 {
+					name, value = strings.TrimSpace(name[:i]), strings.Trim(strings.TrimSpace(name[i+1:]), `"`)
+				}
+				switch name {
+				case "server_no_context_takeover", "client_no_context_takeover", "client_max_window_bits":
+				case "server_max_window_bits":
+					if value != "15" {
+						continue offers
+					}
+				default:
+					continue offers
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,292 @@
+package httpsvr
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// wsClient is a minimal client speaking masked frames.
+type wsClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+	resp *http.Response
+}
+
+func dialWebSocket(s *httptest.Server, header http.Header) (*wsClient, error) {
+	conn, err := net.Dial("tcp", s.Listener.Addr().String())
+	if err != nil {
+		return nil, err
+	}
+	req, _ := http.NewRequest("GET", s.URL+"/ws", nil)
+	req.Header = handshakeHeader(header)
+	req.Header.Set(HeaderConnection, "keep-alive, Upgrade")
+	req.Write(conn)
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	return &wsClient{conn: conn, br: br, resp: resp}, nil
+}
+
+// handshakeHeader returns the headers of a valid handshake, overridden by
+// extra.
+func handshakeHeader(extra http.Header) http.Header {
+	header := http.Header{}
+	header.Set(HeaderConnection, "Upgrade")
+	header.Set(HeaderUpgrade, "websocket")
+	header.Set(HeaderSecWebSocketVersion, "13")
+	header.Set(HeaderSecWebSocketKey, "dGhlIHNhbXBsZSBub25jZQ==")
+	for k, v := range extra {
+		header[http.CanonicalHeaderKey(k)] = v
+	}
+	return header
+}
+
+func (c *wsClient) writeFrame(b0 byte, payload []byte) {
+	frame := []byte{b0, 0x80}
+	switch n := len(payload); {
+	case n <= 125:
+		frame[1] |= byte(n)
+	default:
+		frame[1] |= 126
+		frame = append(frame, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(n))
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	c.conn.Write(frame)
+}
+
+func (c *wsClient) readFrame() (b0 byte, payload []byte, err error) {
+	header := make([]byte, 2)
+	if _, err = io.ReadFull(c.br, header); err != nil {
+		return
+	}
+	n := int(header[1] & 0x7f)
+	if n == 126 {
+		ext := make([]byte, 2)
+		io.ReadFull(c.br, ext)
+		n = int(binary.BigEndian.Uint16(ext))
+	}
+	payload = make([]byte, n)
+	_, err = io.ReadFull(c.br, payload)
+	return header[0], payload, err
+}
+
+func closePayload(code int, text string) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, uint16(code))
+	return append(b, text...)
+}
+
+func Test_WebSocket(t *testing.T) {
+	serve := func(opts WebSocketOptions, handler func(ws *WebSocketConn)) (*httptest.Server, chan error) {
+		errs := make(chan error, 1)
+		e := New()
+		e.GET("/ws", func(ctx *Context) {
+			ctx.SetHeader("X-Session", "abc")
+			ws, err := ctx.UpgradeWebSocket(opts)
+			if err != nil {
+				errs <- err
+				return
+			}
+			handler(ws)
+		})
+		return httptest.NewServer(e), errs
+	}
+
+	Convey("Messages are echoed until the client closes", t, func() {
+		var closeErr error
+		done := make(chan struct{})
+		s, _ := serve(WebSocketOptions{Subprotocols: []string{"chat", "push"}}, func(ws *WebSocketConn) {
+			defer close(done)
+			for {
+				typ, data, err := ws.ReadMessage()
+				if err != nil {
+					closeErr = err
+					return
+				}
+				ws.WriteMessage(typ, data)
+			}
+		})
+		defer s.Close()
+
+		c, err := dialWebSocket(s, http.Header{HeaderSecWebSocketProtocol: {"push, chat"}})
+		So(err, ShouldBeNil)
+		So(c.resp.StatusCode, ShouldEqual, http.StatusSwitchingProtocols)
+		So(c.resp.Header.Get(HeaderSecWebSocketAccept), ShouldEqual, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=")
+		So(c.resp.Header.Get(HeaderSecWebSocketProtocol), ShouldEqual, "chat")
+		So(c.resp.Header.Get("X-Session"), ShouldEqual, "abc")
+
+		c.writeFrame(0x81, []byte("hello"))
+		b0, payload, _ := c.readFrame()
+		So(b0, ShouldEqual, 0x81)
+		So(string(payload), ShouldEqual, "hello")
+
+		// a fragmented binary message with a ping in between
+		c.writeFrame(0x02, []byte("ab"))
+		c.writeFrame(0x89, []byte("p"))
+		c.writeFrame(0x80, bytes.Repeat([]byte("c"), 200))
+		b0, payload, _ = c.readFrame()
+		So(b0, ShouldEqual, 0x8a)
+		So(string(payload), ShouldEqual, "p")
+		b0, payload, _ = c.readFrame()
+		So(b0, ShouldEqual, 0x82)
+		So(string(payload), ShouldEqual, "ab"+strings.Repeat("c", 200))
+
+		c.writeFrame(0x88, closePayload(CloseGoingAway, "bye"))
+		b0, payload, _ = c.readFrame()
+		So(b0, ShouldEqual, 0x88)
+		So(binary.BigEndian.Uint16(payload), ShouldEqual, CloseGoingAway)
+		<-done
+		So(closeErr, ShouldResemble, &CloseError{Code: CloseGoingAway, Text: "bye"})
+	})
+
+	Convey("The server closes without a reader", t, func() {
+		closed := make(chan error, 2)
+		s, _ := serve(WebSocketOptions{}, func(ws *WebSocketConn) {
+			ws.WriteJSON(JSON{"event": "welcome"})
+			closed <- ws.CloseWith(ClosePolicyViolation, "go away")
+			closed <- ws.WriteMessage(TextMessage, []byte("late"))
+		})
+		defer s.Close()
+
+		c, _ := dialWebSocket(s, nil)
+		_, payload, _ := c.readFrame()
+		So(string(payload), ShouldEqual, `{"event":"welcome"}`)
+		b0, payload, _ := c.readFrame()
+		So(b0, ShouldEqual, 0x88)
+		So(string(payload), ShouldEqual, string(closePayload(ClosePolicyViolation, "go away")))
+		c.writeFrame(0x88, payload[:2])
+		So(<-closed, ShouldBeNil)
+		So(<-closed, ShouldEqual, ErrWebSocketClosed)
+	})
+
+	Convey("Protocol violations and large messages close the connection", t, func() {
+		errs := make(chan error, 1)
+		s, _ := serve(WebSocketOptions{ReadLimit: 16}, func(ws *WebSocketConn) {
+			_, _, err := ws.ReadMessage()
+			errs <- err
+		})
+		defer s.Close()
+
+		c, _ := dialWebSocket(s, nil)
+		c.writeFrame(0x82, make([]byte, 17))
+		_, payload, _ := c.readFrame()
+		So(binary.BigEndian.Uint16(payload), ShouldEqual, CloseMessageTooBig)
+		So(<-errs, ShouldEqual, ErrWebSocketReadLimit)
+
+		c, _ = dialWebSocket(s, nil)
+		c.writeFrame(0x81, []byte{0xff})
+		_, payload, _ = c.readFrame()
+		So(binary.BigEndian.Uint16(payload), ShouldEqual, CloseInvalidPayload)
+		<-errs
+
+		c, _ = dialWebSocket(s, nil)
+		c.writeFrame(0x80, []byte("orphan"))
+		_, payload, _ = c.readFrame()
+		So(binary.BigEndian.Uint16(payload), ShouldEqual, CloseProtocolError)
+		<-errs
+	})
+
+	Convey("permessage-deflate compresses the messages", t, func() {
+		s, _ := serve(WebSocketOptions{Compression: true}, func(ws *WebSocketConn) {
+			typ, data, err := ws.ReadMessage()
+			if err == nil {
+				ws.WriteMessage(typ, data)
+			}
+		})
+		defer s.Close()
+
+		c, _ := dialWebSocket(s, http.Header{HeaderSecWebSocketExtensions: {"permessage-deflate; client_max_window_bits"}})
+		So(c.resp.Header.Get(HeaderSecWebSocketExtensions), ShouldStartWith, "permessage-deflate")
+
+		text := strings.Repeat("compress me ", 50)
+		compressed, _ := deflateMessage([]byte(text), 6)
+		c.writeFrame(0xc1, compressed)
+		b0, payload, _ := c.readFrame()
+		So(b0, ShouldEqual, 0xc1)
+		So(len(payload), ShouldBeLessThan, len(text))
+		data, err := inflateMessage(payload, 1<<20)
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, text)
+
+		offer := http.Header{}
+		offer.Set(HeaderSecWebSocketExtensions, "permessage-deflate; server_max_window_bits=10")
+		So(negotiateDeflate(offer), ShouldBeFalse)
+		offer.Set(HeaderSecWebSocketExtensions, "x-webkit, permessage-deflate; server_max_window_bits=15")
+		So(negotiateDeflate(offer), ShouldBeTrue)
+	})
+
+	Convey("Keepalive pings the client", t, func() {
+		s, _ := serve(WebSocketOptions{PingInterval: 20 * time.Millisecond}, func(ws *WebSocketConn) {
+			ws.ReadMessage()
+		})
+		defer s.Close()
+
+		c, _ := dialWebSocket(s, nil)
+		b0, _, err := c.readFrame()
+		So(err, ShouldBeNil)
+		So(b0, ShouldEqual, 0x89)
+		// without pongs the server gives up
+		for err == nil {
+			_, _, err = c.readFrame()
+		}
+		So(err, ShouldEqual, io.EOF)
+	})
+
+	Convey("Invalid handshakes are refused", t, func() {
+		e := New()
+		var err error
+		e.GET("/ws", func(ctx *Context) {
+			_, err = ctx.UpgradeWebSocket(WebSocketOptions{AllowOrigins: []string{"https://*.example.com"}})
+		})
+		So(performRequestWith(e, "GET", "/ws", handshakeHeader(http.Header{HeaderUpgrade: {"h2c"}}), nil).Code, ShouldEqual, http.StatusBadRequest)
+		So(err, ShouldNotBeNil)
+		w := performRequestWith(e, "GET", "/ws", handshakeHeader(http.Header{HeaderSecWebSocketVersion: {"8"}}), nil)
+		So(w.Code, ShouldEqual, http.StatusUpgradeRequired)
+		So(w.Header().Get(HeaderSecWebSocketVersion), ShouldEqual, "13")
+		So(performRequestWith(e, "GET", "/ws", handshakeHeader(http.Header{HeaderSecWebSocketKey: {"short"}}), nil).Code, ShouldEqual, http.StatusBadRequest)
+		So(performRequestWith(e, "GET", "/ws", handshakeHeader(http.Header{HeaderOrigin: {"https://evil.com"}}), nil).Code, ShouldEqual, http.StatusForbidden)
+		// the recorder can not be hijacked
+		So(performRequestWith(e, "GET", "/ws", handshakeHeader(http.Header{HeaderOrigin: {"https://app.example.com"}}), nil).Code, ShouldEqual, http.StatusInternalServerError)
+
+		req, _ := http.NewRequest("GET", "/ws", nil)
+		req.Host = "example.com"
+		req.Header.Set(HeaderOrigin, "http://example.com")
+		So(websocketOriginAllowed(req, &WebSocketOptions{}), ShouldBeTrue)
+		req.Header.Set(HeaderOrigin, "http://other.com")
+		So(websocketOriginAllowed(req, &WebSocketOptions{}), ShouldBeFalse)
+	})
+
+	Convey("Handlers under Timeout can not upgrade", t, func() {
+		var err error
+		e := New()
+		e.GET("/ws", Timeout(time.Second), func(ctx *Context) {
+			_, err = ctx.UpgradeWebSocket()
+		})
+		s := httptest.NewServer(e)
+		defer s.Close()
+
+		client, dialErr := dialWebSocket(s, nil)
+		So(dialErr, ShouldBeNil)
+		defer client.conn.Close()
+		So(client.resp.StatusCode, ShouldEqual, http.StatusInternalServerError)
+		So(err.Error(), ShouldContainSubstring, ErrHijackTimeout.Error())
+	})
+}
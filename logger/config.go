@@ -58,7 +58,7 @@ func (log Logger) LoadConfigurationDetails(typeList []string, sec config.Section
 	for _, typ := range typeList {
 		typ = strings.TrimSpace(typ)
 		var filt, errFilt LogWriter
-		var lvl level
+		var lvl Level
 		var debugLevel string
 		bad, good := false, true
 		debugLevel, err = sec.GetValue(typ + ".level")
@@ -143,7 +143,7 @@ func newFileLogWriter(prefix string, sec config.Section) (*FileLogWriter, bool)
 		//fmt.Println(key, val)
 	}
 	var file string
-	format := "[%D %T] [%L] (%S) %M"
+	format := "[%D %T] [%L] (%S) [%R] %M"
 	maxlines := 0
 	maxsize := 0
 	daily := true
@@ -25,7 +25,7 @@ func (log Logger) LoadConfigurationDetailsV2(typeList []string, cfg cfgstruct.Lo
 	for _, typ := range typeList {
 		typ = strings.TrimSpace(typ)
 		var filt, errFilt LogWriter
-		var lvl level
+		var lvl Level
 		var enabled bool
 
 		switch typ {
@@ -53,7 +53,7 @@ func (log Logger) LoadConfigurationDetailsV2(typeList []string, cfg cfgstruct.Lo
 	return
 }
 
-func getLevel(levelStr string) (lvl level, err error) {
+func getLevel(levelStr string) (lvl Level, err error) {
 	switch levelStr {
 	case "", "DEBUG":
 		lvl = DEBUG
@@ -71,7 +71,7 @@ func getLevel(levelStr string) (lvl level, err error) {
 	return
 }
 
-func newStdoutLogWriterV2(cfg cfgstruct.LogTypeSt) (w StdoutLogWriter, lvl level, enabled bool, err error) {
+func newStdoutLogWriterV2(cfg cfgstruct.LogTypeSt) (w StdoutLogWriter, lvl Level, enabled bool, err error) {
 	if !cfg.StdoutEnabled {
 		return
 	}
@@ -84,7 +84,7 @@ func newStdoutLogWriterV2(cfg cfgstruct.LogTypeSt) (w StdoutLogWriter, lvl level
 	return
 }
 
-func newFileLogWriterV2(cfg cfgstruct.LogTypeSt) (w *FileLogWriter, lvl level, enabled bool, err error) {
+func newFileLogWriterV2(cfg cfgstruct.LogTypeSt) (w *FileLogWriter, lvl Level, enabled bool, err error) {
 	if !cfg.FileEnable {
 		return
 	}
@@ -115,7 +115,7 @@ func newFileLogWriterV2(cfg cfgstruct.LogTypeSt) (w *FileLogWriter, lvl level, e
 
 	format := strings.Trim(cfg.FileFormat, " \r\n")
 	if len(format) == 0 {
-		format = "[%D %T] [%L] (%S) %M"
+		format = "[%D %T] [%L] (%S) [%R] %M"
 	}
 
 	w = NewFileLogWriter(file, !cfg.FileNoRotate)
@@ -0,0 +1,154 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	traceIDKey
+)
+
+// WithRequestID returns a copy of ctx carrying the request id, the records
+// logged through Ctx(ctx) have it in LogRecord.RequestID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFrom returns the request id carried by ctx, or "".
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithTraceID returns a copy of ctx carrying the trace id, the records
+// logged through Ctx(ctx) have it in LogRecord.TraceID.
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey, id)
+}
+
+// TraceIDFrom returns the trace id carried by ctx, or "".
+func TraceIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDKey).(string)
+	return id
+}
+
+// Entry logs records tagged with the ids of a context, see Ctx.
+type Entry struct {
+	l         Logger
+	requestID string
+	traceID   string
+}
+
+// Ctx returns an Entry logging to Global with the ids carried by ctx, e.g.
+// in a handler:
+//
+//	logger.Ctx(req.Context()).Info("loading user %d", id)
+//
+// The goroutines started by the handler keep the ids as long as they are
+// given ctx. Only the records logged through an Entry carry the ids, those of
+// the package-level functions such as Info show - for %R and %X.
+func Ctx(ctx context.Context) Entry {
+	return Global.Ctx(ctx)
+}
+
+// Ctx returns an Entry logging to log with the ids carried by ctx.
+func (log Logger) Ctx(ctx context.Context) Entry {
+	return Entry{l: log, requestID: RequestIDFrom(ctx), traceID: TraceIDFrom(ctx)}
+}
+
+// Debug : see the Debug wrapper
+func (e Entry) Debug(arg0 interface{}, args ...interface{}) {
+	e.log(DEBUG, arg0, args)
+}
+
+// Trace : see the Debug wrapper
+func (e Entry) Trace(arg0 interface{}, args ...interface{}) {
+	e.log(TRACE, arg0, args)
+}
+
+// Info : see the Debug wrapper
+func (e Entry) Info(arg0 interface{}, args ...interface{}) {
+	e.log(INFO, arg0, args)
+}
+
+// Warn : see the Warn wrapper
+func (e Entry) Warn(arg0 interface{}, args ...interface{}) error {
+	return e.log(WARNING, arg0, args)
+}
+
+// Error : see the Error wrapper
+func (e Entry) Error(arg0 interface{}, args ...interface{}) error {
+	return e.log(ERROR, arg0, args)
+}
+
+// log writes the record of the Entry methods, their caller is the source.
+func (e Entry) log(lvl Level, arg0 interface{}, args []interface{}) error {
+	enabled := e.l.enabled(lvl)
+	if !enabled && lvl < WARNING {
+		return nil
+	}
+
+	var msg string
+	switch first := arg0.(type) {
+	case string:
+		msg = first
+		if len(args) > 0 {
+			msg = fmt.Sprintf(first, args...)
+		}
+	case func() string:
+		msg = first()
+	default:
+		msg = fmt.Sprint(first) + fmt.Sprintf(strings.Repeat(" %v", len(args)), args...)
+	}
+	if !enabled {
+		return errors.New(msg)
+	}
+
+	src := ""
+	if pc, file, line, ok := runtime.Caller(2); ok {
+		src = fmt.Sprintf("%s %s:%d", runtime.FuncForPC(pc).Name(), filepath.Base(file), line)
+	}
+	e.l.WriteRecord(&LogRecord{
+		Level:     lvl,
+		Created:   time.Now(),
+		Source:    src,
+		Message:   msg,
+		RequestID: e.requestID,
+		TraceID:   e.traceID,
+	})
+	return errors.New(msg)
+}
+
+// WriteRecord dispatches rec, built by the caller, to the filters of its
+// level.
+func (log Logger) WriteRecord(rec *LogRecord) {
+	for _, filt := range log {
+		if rec.Level < filt.Level {
+			continue
+		}
+		filt.LogWrite(rec)
+	}
+}
+
+// WriteRecord : Wrapper for (*Logger).WriteRecord
+func WriteRecord(rec *LogRecord) {
+	Global.WriteRecord(rec)
+}
+
+func (log Logger) enabled(lvl Level) bool {
+	for _, filt := range log {
+		if lvl >= filt.Level {
+			return true
+		}
+	}
+	return false
+}
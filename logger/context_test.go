@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordWriter []*LogRecord
+
+func (w *recordWriter) LogWrite(rec *LogRecord) { *w = append(*w, rec) }
+
+func (w *recordWriter) Close() {}
+
+func TestContextIDs(t *testing.T) {
+	ctx := context.Background()
+	if RequestIDFrom(ctx) != "" || TraceIDFrom(ctx) != "" {
+		t.Fatalf("Background should carry no ids")
+	}
+	ctx = WithTraceID(WithRequestID(ctx, "rid"), "tid")
+	if id := RequestIDFrom(ctx); id != "rid" {
+		t.Errorf("RequestIDFrom returned %q, want %q", id, "rid")
+	}
+	if id := TraceIDFrom(ctx); id != "tid" {
+		t.Errorf("TraceIDFrom returned %q, want %q", id, "tid")
+	}
+}
+
+func TestEntry(t *testing.T) {
+	w := &recordWriter{}
+	l := make(Logger)
+	l.AddFilter("records", INFO, w)
+	ctx := WithTraceID(WithRequestID(context.Background(), "rid"), "tid")
+
+	l.Ctx(ctx).Info("user %d", 7)
+	if len(*w) != 1 {
+		t.Fatalf("Info wrote %d records, want 1", len(*w))
+	}
+	rec := (*w)[0]
+	if rec.Message != "user 7" {
+		t.Errorf("Message is %q, want %q", rec.Message, "user 7")
+	}
+	if rec.RequestID != "rid" || rec.TraceID != "tid" {
+		t.Errorf("ids are %q and %q, want rid and tid", rec.RequestID, rec.TraceID)
+	}
+	if rec.Level != INFO {
+		t.Errorf("Level is %v, want %v", rec.Level, INFO)
+	}
+	if !strings.Contains(rec.Source, "TestEntry context_test.go") {
+		t.Errorf("Source is %q, want the caller of Info", rec.Source)
+	}
+
+	called := false
+	l.Ctx(ctx).Debug(func() string { called = true; return "hidden" })
+	if called || len(*w) != 1 {
+		t.Errorf("Debug below the filter level should not log nor run the closure")
+	}
+
+	if err := l.Ctx(ctx).Error("failed: %s", "io"); err == nil || err.Error() != "failed: io" {
+		t.Errorf("Error returned %v, want failed: io", err)
+	}
+	if len(*w) != 2 || (*w)[1].RequestID != "rid" {
+		t.Errorf("Error should write a tagged record")
+	}
+
+	l.Ctx(context.Background()).Info("plain")
+	if rec := (*w)[2]; rec.RequestID != "" || rec.Message != "plain" {
+		t.Errorf("a context without ids should write plain records, got %+v", rec)
+	}
+}
+
+func TestWriteRecord(t *testing.T) {
+	w := &recordWriter{}
+	l := make(Logger)
+	l.AddFilter("records", WARNING, w)
+	l.WriteRecord(&LogRecord{Level: INFO, Message: "dropped"})
+	l.WriteRecord(&LogRecord{Level: ERROR, Message: "kept", RequestID: "rid"})
+	if len(*w) != 1 || (*w)[0].Message != "kept" {
+		t.Errorf("WriteRecord should dispatch by level, got %d records", len(*w))
+	}
+}
+
+func TestFormatIDs(t *testing.T) {
+	rec := &LogRecord{Level: INFO, Created: time.Unix(0, 0), Message: "message", RequestID: "rid", TraceID: "tid"}
+	if out := FormatLogRecord("[%R] [%X] %M", rec); out != "[rid] [tid] message\n" {
+		t.Errorf("FormatLogRecord returned %q", out)
+	}
+	rec.RequestID, rec.TraceID = "", ""
+	if out := FormatLogRecord("[%R] [%X] %M", rec); out != "[-] [-] message\n" {
+		t.Errorf("FormatLogRecord returned %q without ids", out)
+	}
+}
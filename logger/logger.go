@@ -47,12 +47,12 @@ import (
 	"time"
 )
 
-// These are the integer logging levels used by the logger
-type level int
+// Level is one of the integer logging levels used by the logger
+type Level int
 
 // LEVEL define
 const (
-	DEBUG level = iota
+	DEBUG Level = iota
 	TRACE
 	INFO
 	WARNING
@@ -64,7 +64,7 @@ var (
 	levelStrings = [...]string{"DEBG", "TRAC", "INFO", "WARN", "EROR"}
 )
 
-func (l level) String() string {
+func (l Level) String() string {
 	if l < 0 || int(l) > len(levelStrings) {
 		return "UNKNOWN"
 	}
@@ -79,10 +79,12 @@ var (
 
 // LogRecord contains all of the pertinent information for each message
 type LogRecord struct {
-	Level   level     // The log level
-	Created time.Time // The time at which the log message was created (nanoseconds)
-	Source  string    // The message source
-	Message string    // The log message
+	Level     Level     // The log level
+	Created   time.Time // The time at which the log message was created (nanoseconds)
+	Source    string    // The message source
+	Message   string    // The log message
+	RequestID string    // The request being handled, see WithRequestID
+	TraceID   string    // The trace of the request, see WithTraceID
 }
 
 // LogWriter : This is an interface for anything that should be able to write logs
@@ -98,7 +100,7 @@ type LogWriter interface {
 // Filter represents the log level below which no log records are written to
 // the associated LogWriter.
 type Filter struct {
-	Level level
+	Level Level
 	LogWriter
 }
 
@@ -116,7 +118,7 @@ func NewLogger() Logger {
 // or above lvl to standard output.
 //
 // DEPRECATED: use NewDefaultLogger instead.
-func NewStdoutLogger(lvl level) Logger {
+func NewStdoutLogger(lvl Level) Logger {
 	os.Stderr.WriteString("warning: use of deprecated NewStdoutLogger\n")
 	return Logger{
 		"stdout": &Filter{lvl, NewStdoutLogWriter()},
@@ -125,7 +127,7 @@ func NewStdoutLogger(lvl level) Logger {
 
 // NewDefaultLogger : Create a new logger with a "stdout" filter configured to send log messages at
 // or above lvl to standard output.
-func NewDefaultLogger(lvl level) Logger {
+func NewDefaultLogger(lvl Level) Logger {
 	return Logger{
 		"stdout": &Filter{lvl, NewStdoutLogWriter()},
 	}
@@ -146,13 +148,13 @@ func (log Logger) Close() {
 // AddFilter : Add a new LogWriter to the Logger which will only log messages at lvl or
 // higher.  This function should not be called from multiple goroutines.
 // Returns the logger for chaining.
-func (log Logger) AddFilter(name string, lvl level, writer LogWriter) Logger {
+func (log Logger) AddFilter(name string, lvl Level, writer LogWriter) Logger {
 	log[name] = &Filter{lvl, writer}
 	return log
 }
 
 // Send a formatted log message internally
-func (log Logger) intLogf(lvl level, format string, args ...interface{}) {
+func (log Logger) intLogf(lvl Level, format string, args ...interface{}) {
 	skip := true
 
 	// Determine if any logging will be done
@@ -201,7 +203,7 @@ func (log Logger) intLogf(lvl level, format string, args ...interface{}) {
 }
 
 // Send a closure log message internally
-func (log Logger) intLogc(lvl level, closure func() string) {
+func (log Logger) intLogc(lvl Level, closure func() string) {
 	skip := true
 
 	// Determine if any logging will be done
@@ -241,7 +243,7 @@ func (log Logger) intLogc(lvl level, closure func() string) {
 }
 
 // Log : Send a log message with manual level, source, and message.
-func (log Logger) Log(lvl level, source, message string) {
+func (log Logger) Log(lvl Level, source, message string) {
 	skip := true
 
 	// Determine if any logging will be done
@@ -274,13 +276,13 @@ func (log Logger) Log(lvl level, source, message string) {
 
 // Logf logs a formatted log message at the given log level, using the caller as
 // its source.
-func (log Logger) Logf(lvl level, format string, args ...interface{}) {
+func (log Logger) Logf(lvl Level, format string, args ...interface{}) {
 	log.intLogf(lvl, format, args...)
 }
 
 // Logc logs a string returned by the closure at the given log level, using the caller as
 // its source.  If no log message would be written, the closure is never called.
-func (log Logger) Logc(lvl level, closure func() string) {
+func (log Logger) Logc(lvl Level, closure func() string) {
 	log.intLogc(lvl, closure)
 }
 
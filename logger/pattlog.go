@@ -23,8 +23,10 @@ var formatCache = &formatCacheType{}
 // %L - Level (FNST, FINE, DEBG, TRAC, WARN, EROR, CRIT)
 // %S - Source
 // %M - Message
+// %R - Request id, see WithRequestID, or - when the record has none
+// %X - Trace id, see WithTraceID, or - when the record has none
 // Ignores unknown formats
-// Recommended: "[%D %T] [%L] (%S) %M"
+// Recommended: "[%D %T] [%L] (%S) [%R] %M"
 func FormatLogRecord(format string, rec *LogRecord) string {
 	return FormatLogRecordEx(format, rec, "\n")
 }
@@ -91,6 +93,10 @@ func FormatLogRecordEx(format string, rec *LogRecord, suffix string) string {
 				out.WriteString(source)
 			case 'M':
 				out.WriteString(rec.Message)
+			case 'R':
+				out.WriteString(orDash(rec.RequestID))
+			case 'X':
+				out.WriteString(orDash(rec.TraceID))
 			}
 			if len(piece) > 1 {
 				out.Write(piece[1:])
@@ -103,3 +109,11 @@ func FormatLogRecordEx(format string, rec *LogRecord, suffix string) string {
 
 	return out.String()
 }
+
+// orDash returns s, or "-" when it is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
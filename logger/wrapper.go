@@ -30,7 +30,7 @@ func LoadConfigurationConfV2(cfg cfgstruct.LogTypeSt) {
 }
 
 // AddFilter : Wrapper for (*Logger).AddFilter
-func AddFilter(name string, lvl level, writer LogWriter) {
+func AddFilter(name string, lvl Level, writer LogWriter) {
 	Global.AddFilter(name, lvl, writer)
 }
 
@@ -96,19 +96,19 @@ func Stdoutf(format string, args ...interface{}) {
 
 // Log : Send a log message manually
 // Wrapper for (*Logger).Log
-func Log(lvl level, source, message string) {
+func Log(lvl Level, source, message string) {
 	Global.Log(lvl, source, message)
 }
 
 // Logf : Send a formatted log message easily
 // Wrapper for (*Logger).Logf
-func Logf(lvl level, format string, args ...interface{}) {
+func Logf(lvl Level, format string, args ...interface{}) {
 	Global.intLogf(lvl, format, args...)
 }
 
 // Logc : Send a closure log message
 // Wrapper for (*Logger).Logc
-func Logc(lvl level, closure func() string) {
+func Logc(lvl Level, closure func() string) {
 	Global.intLogc(lvl, closure)
 }
 